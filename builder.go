@@ -0,0 +1,268 @@
+package simplequery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Expr is a condition or a value built in Go instead of a query text, e.g.
+// And(Key("amount").Gt(100), Not(Exists("blocked"))). Operands which are no
+// Expr are literals converted with ValueOf, so texts need no quoting. Errors
+// like an invalid key are returned by Query.
+type Expr struct {
+	n   node
+	err error
+}
+
+// Key references a key of the data, e.g. amount, customer.name or prices.*.
+func Key(name string) Expr {
+	if !isName(name, IDENT) {
+		return Expr{err: fmt.Errorf("invalid key %q", name)}
+	}
+	return Expr{n: &keyNode{name: name}}
+}
+
+// Literal is a constant value converted with ValueOf. A time is written as
+// text, lists and objects can not be written as literal.
+func Literal(value any) Expr {
+	v := ValueOf(value)
+	if v.Kind() == KindList || v.Kind() == KindObject {
+		return Expr{err: fmt.Errorf("%s can not be written as literal", v.Kind())}
+	}
+	return Expr{n: &literalNode{value: v}}
+}
+
+// Param is a named placeholder :name, an empty name is a positional ?.
+func Param(name string) Expr {
+	if name != "" && !isName(":"+name, PARAM) {
+		return Expr{err: fmt.Errorf("invalid parameter name %q", name)}
+	}
+	return Expr{n: &paramNode{name: name}}
+}
+
+// Macro references a macro of the library passed with WithLibrary to Query.
+func Macro(name string) Expr {
+	if !isMacroName(name) {
+		return Expr{err: fmt.Errorf("invalid macro name %q", name)}
+	}
+	return Expr{n: &macroNode{macro: &macro{name: name}}}
+}
+
+// Call calls a function with the arguments.
+func Call(name string, args ...any) Expr {
+	if !isName(name, IDENT) {
+		return Expr{err: fmt.Errorf("invalid function name %q", name)}
+	}
+
+	n := &callNode{name: name}
+	for _, arg := range args {
+		x := operand(arg)
+		if x.err != nil {
+			return x
+		}
+		n.args = append(n.args, x.n)
+	}
+	return Expr{n: n}
+}
+
+// And is true if all conditions are true.
+func And(conditions ...Expr) Expr {
+	return logical(AND, conditions)
+}
+
+// Or is true if one of the conditions is true.
+func Or(conditions ...Expr) Expr {
+	return logical(OR, conditions)
+}
+
+// Not negates the condition.
+func Not(condition Expr) Expr {
+	return condition.wrap(func(x node) node { return &notNode{x: x} })
+}
+
+// Neg is the unary minus of a number.
+func Neg(value Expr) Expr {
+	return value.wrap(func(x node) node { return &negNode{x: x} })
+}
+
+// Exists tests whether the key exists.
+func Exists(key string) Expr {
+	return Key(key).predicate(EXISTS, false)
+}
+
+// Any tests whether the condition is true for an item of the list. With an
+// empty key the condition must contain a wildcard key like prices.*.
+func Any(key string, condition Expr) Expr {
+	return quantifier(ANY, key, condition)
+}
+
+// All tests whether the condition is true for all items of the list.
+func All(key string, condition Expr) Expr {
+	return quantifier(ALL, key, condition)
+}
+
+// None tests whether the condition is false for all items of the list.
+func None(key string, condition Expr) Expr {
+	return quantifier(NONE, key, condition)
+}
+
+// And is true if the expression and all conditions are true.
+func (e Expr) And(conditions ...Expr) Expr {
+	return logical(AND, append([]Expr{e}, conditions...))
+}
+
+// Or is true if the expression or one of the conditions is true.
+func (e Expr) Or(conditions ...Expr) Expr {
+	return logical(OR, append([]Expr{e}, conditions...))
+}
+
+// Eq compares with =.
+func (e Expr) Eq(value any) Expr { return e.binary(EQ, value) }
+
+// Ne compares with !=.
+func (e Expr) Ne(value any) Expr { return e.binary(NE, value) }
+
+// Gt compares with >.
+func (e Expr) Gt(value any) Expr { return e.binary(GT, value) }
+
+// Gte compares with >=.
+func (e Expr) Gte(value any) Expr { return e.binary(GTE, value) }
+
+// Lt compares with <.
+func (e Expr) Lt(value any) Expr { return e.binary(LT, value) }
+
+// Lte compares with <=.
+func (e Expr) Lte(value any) Expr { return e.binary(LTE, value) }
+
+// Plus adds the value.
+func (e Expr) Plus(value any) Expr { return e.binary(PLUS, value) }
+
+// Minus subtracts the value.
+func (e Expr) Minus(value any) Expr { return e.binary(MINUS, value) }
+
+// Mul multiplies with the value.
+func (e Expr) Mul(value any) Expr { return e.binary(MUL, value) }
+
+// Div divides by the value.
+func (e Expr) Div(value any) Expr { return e.binary(DIV, value) }
+
+// Mod is the remainder of the division by the value.
+func (e Expr) Mod(value any) Expr { return e.binary(MOD, value) }
+
+// IsEmpty tests the key with IS EMPTY, IsEmpty(false) with IS NOT EMPTY.
+func (e Expr) IsEmpty(is bool) Expr { return e.predicate(EMPTY, !is) }
+
+// IsMissing tests the key with IS MISSING, IsMissing(false) with IS NOT MISSING.
+func (e Expr) IsMissing(is bool) Expr { return e.predicate(MISSING, !is) }
+
+// IsBlank tests the key with IS BLANK, IsBlank(false) with IS NOT BLANK.
+func (e Expr) IsBlank(is bool) Expr { return e.predicate(BLANK, !is) }
+
+// IsNull tests the key with IS NULL, IsNull(false) with IS NOT NULL.
+func (e Expr) IsNull(is bool) Expr { return e.predicate(NULL, !is) }
+
+// Query compiles the expression with the options. The query is the same as
+// the compiled text of String, e.g. the functions must exist and comparisons
+// must have values.
+func (e Expr) Query(opts ...Option) (*Query, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if e.n == nil {
+		return nil, errors.New("expression is empty")
+	}
+
+	return Compile(e.String(), opts...)
+}
+
+// String returns the expression as query text in canonical form, see Format.
+func (e Expr) String() string {
+	if e.n == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	format(&b, e.n)
+	return b.String()
+}
+
+func (e Expr) binary(op Token, value any) Expr {
+	right := operand(value)
+	if e.err != nil {
+		return e
+	}
+	if right.err != nil {
+		return right
+	}
+	return Expr{n: &binaryNode{op: op, left: e.n, right: right.n}}
+}
+
+func (e Expr) predicate(predicate Token, negate bool) Expr {
+	if e.err != nil {
+		return e
+	}
+
+	key, ok := e.n.(*keyNode)
+	if !ok {
+		return Expr{err: fmt.Errorf("%s needs a key", tokens[predicate])}
+	}
+	return Expr{n: &isNode{key: key, predicate: predicate, negate: negate}}
+}
+
+func (e Expr) wrap(fn func(node) node) Expr {
+	if e.err != nil || e.n == nil {
+		return e
+	}
+	return Expr{n: fn(e.n)}
+}
+
+// operand returns an Expr or a literal of the value.
+func operand(value any) Expr {
+	if x, ok := value.(Expr); ok {
+		if x.n == nil && x.err == nil {
+			return Expr{err: errors.New("expression is empty")}
+		}
+		return x
+	}
+	return Literal(value)
+}
+
+func logical(op Token, conditions []Expr) Expr {
+	if len(conditions) == 0 {
+		return Expr{err: fmt.Errorf("%s needs a condition", tokens[op])}
+	}
+
+	x := operand(conditions[0])
+	for _, condition := range conditions[1:] {
+		condition = operand(condition)
+		if x.err != nil {
+			return x
+		}
+		if condition.err != nil {
+			return condition
+		}
+		x = Expr{n: &binaryNode{op: op, left: x.n, right: condition.n}}
+	}
+	return x
+}
+
+func quantifier(quantifier Token, key string, condition Expr) Expr {
+	if condition.err != nil {
+		return condition
+	}
+
+	if key == "" {
+		keys := wildcardKeys(condition.n)
+		if len(keys) == 0 {
+			return Expr{err: fmt.Errorf("%s needs a key or a wildcard key", tokens[quantifier])}
+		}
+		return Expr{n: &quantifierNode{quantifier: quantifier, key: keys[0], x: condition.n, wildcard: true}}
+	}
+
+	k := Key(key)
+	if k.err != nil {
+		return k
+	}
+	return Expr{n: &quantifierNode{quantifier: quantifier, key: k.n.(*keyNode), x: condition.n}}
+}
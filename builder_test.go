@@ -0,0 +1,87 @@
+package simplequery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	library := testLibrary(t)
+
+	testCases := []struct {
+		expr  Expr
+		query string
+	}{
+		{expr: And(Key("amount").Gt(100), Not(Exists("blocked"))), query: "amount > 100 AND !EXISTS blocked"},
+		{expr: Or(Key("a"), And(Key("b"), Key("c"))), query: "a OR (b AND c)"},
+		{expr: And(Or(Key("a"), Key("b")), Key("c")), query: "a OR b AND c"},
+		{expr: And(Key("a"), Or(Key("b"), Key("c"))), query: "a AND (b OR c)"},
+		{expr: And(Key("a")), query: "a"},
+		{expr: Not(Or(Key("a"), Key("b"))), query: "!(a OR b)"},
+		{expr: Key("name").Eq(`say "hi" AND x`), query: `name = "say \"hi\" AND x"`},
+		{expr: Key("region").Eq("north"), query: `region = "north"`},
+		{expr: Key("region").Ne(Key("home")), query: "region != (home)"},
+		{expr: Key("a").Gte(1.5).And(Key("b").Eq(nil)), query: "a >= 1.5 AND b = NULL"},
+		{expr: Key("a").Plus(Key("b")).Mul(Key("c")).Lt(Neg(Key("d").Minus(1))), query: "(a + b) * c < -(d - 1)"},
+		{expr: Key("a").Div(2).Mod(3).Eq(Literal(true)), query: "a / 2 % 3 = TRUE"},
+		{expr: Key("a").Gt(1).Eq(Key("b").Lt(2)), query: "(a > 1) = (b < 2)"},
+		{expr: Call("lower", Key("name")).Eq("anna"), query: `lower(name) = "anna"`},
+		{expr: Key("x").IsEmpty(false).And(Key("y").IsNull(true)), query: "x IS NOT EMPTY AND y IS NULL"},
+		{expr: Key("not").Eq("empty").And(Key("missing").IsBlank(true)), query: `not = "empty" AND missing IS BLANK`},
+		{expr: Key("x").IsMissing(true).And(Key("y").IsBlank(false)), query: "x IS MISSING AND y IS NOT BLANK"},
+		{expr: And(Any("items", Or(Key("sku").Eq("a"), Key("qty").Gt(1))), None("tags", Not(Key("x")))), query: `ANY items: (sku = "a" OR qty > 1) AND NONE tags: !x`},
+		{expr: All("", Key("prices.*").Gt(1)), query: "ALL prices.* > 1"},
+		{expr: Any("all", Key("none")).And(Key("any").Eq("none")), query: `ANY all: none AND any = "none"`},
+		{expr: Key("amount").Gt(Param("min")).And(Key("b").Eq(Param(""))), query: "amount > :min AND b = ?"},
+		{expr: Macro("isEU").And(Macro("gross").Gt(100)), query: "@isEU AND @gross > 100"},
+		{expr: Key("created").Lt(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)), query: `created < "2024-01-02T00:00:00Z"`},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.query, testCase.expr.String())
+
+		q, err := testCase.expr.Query(WithLibrary(library))
+		if !assert.NoError(t, err, testCase.query) {
+			continue
+		}
+
+		parsed, err := Compile(testCase.query, WithLibrary(library))
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, parsed, q, testCase.query)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expr Expr
+		err  string
+	}{
+		{expr: Key("a b"), err: `invalid key "a b"`},
+		{expr: Key("and").Eq(1), err: `invalid key "and"`},
+		{expr: Exists(""), err: `invalid key ""`},
+		{expr: And(Key("a"), Key("b").Eq([]any{1})), err: "list can not be written as literal"},
+		{expr: And(), err: "AND needs a condition"},
+		{expr: Or(Key("a"), Expr{}), err: "expression is empty"},
+		{expr: Expr{}, err: "expression is empty"},
+		{expr: Call("a-b"), err: `invalid function name "a-b"`},
+		{expr: Param("1x"), err: `invalid parameter name "1x"`},
+		{expr: Macro("a b"), err: `invalid macro name "a b"`},
+		{expr: Call("lower", Key("a")).IsEmpty(true), err: "EMPTY needs a key"},
+		{expr: Any("", Key("a").Gt(1)), err: "ANY needs a key or a wildcard key"},
+		{expr: Call("nope"), err: "nope() calls an unknown function"},
+		{expr: Key("a").Plus(1), err: "is no condition"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := testCase.expr.Query()
+		if assert.Error(t, err, testCase.err) {
+			assert.Contains(t, err.Error(), testCase.err)
+		}
+	}
+}
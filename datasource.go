@@ -0,0 +1,283 @@
+package simplequery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DataSource provides the values of the keys used in a query. Lookup is only
+// called for keys the query needs, so a data source can load them lazily.
+type DataSource interface {
+	// Lookup returns the value of the key and whether the key exists.
+	Lookup(key string) (Value, bool)
+}
+
+// KeyScanner is a DataSource which can list its keys. Wildcard keys like
+// approval.* only match keys of data sources and objects which are a KeyScanner.
+type KeyScanner interface {
+	DataSource
+	// ScanPrefix calls fn with each key starting with the prefix and its value
+	// until fn returns false.
+	ScanPrefix(prefix string, fn func(key string, value Value) bool)
+}
+
+// ContextDataSource is a DataSource whose lookups can block, e.g. on a store.
+// MatchContext passes its context to LookupContext, an error fails the node
+// which looks up the key.
+type ContextDataSource interface {
+	DataSource
+	// LookupContext returns the value of the key and whether the key exists.
+	LookupContext(ctx context.Context, key string) (Value, bool, error)
+}
+
+// ContextDataSourceFunc adapts a function to a ContextDataSource. Lookup
+// calls it with the background context and ignores the error.
+type ContextDataSourceFunc func(ctx context.Context, key string) (Value, bool, error)
+
+// Lookup calls the function with the background context.
+func (f ContextDataSourceFunc) Lookup(key string) (Value, bool) {
+	value, ok, _ := f(context.Background(), key)
+	return value, ok
+}
+
+// LookupContext calls the function.
+func (f ContextDataSourceFunc) LookupContext(ctx context.Context, key string) (Value, bool, error) {
+	return f(ctx, key)
+}
+
+// contextSource passes the context of an evaluation to a ContextDataSource and
+// keeps the error of the last lookup.
+type contextSource struct {
+	ctx  context.Context
+	data ContextDataSource
+	err  error
+}
+
+func (s *contextSource) Lookup(key string) (Value, bool) {
+	value, ok, err := s.data.LookupContext(s.ctx, key)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	return value, ok
+}
+
+func (s *contextSource) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	if scanner, ok := s.data.(KeyScanner); ok {
+		scanner.ScanPrefix(prefix, fn)
+	}
+}
+
+// DataSourceFunc adapts a function to a DataSource.
+type DataSourceFunc func(key string) (Value, bool)
+
+// Lookup calls the function.
+func (f DataSourceFunc) Lookup(key string) (Value, bool) {
+	return f(key)
+}
+
+// StringMap is a DataSource of texts.
+type StringMap map[string]string
+
+// Lookup returns the text of the key.
+func (m StringMap) Lookup(key string) (Value, bool) {
+	value, ok := m[key]
+	return StringValue(value), ok
+}
+
+// ScanPrefix calls fn with the keys starting with the prefix.
+func (m StringMap) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	for key, value := range m {
+		if strings.HasPrefix(key, prefix) && !fn(key, StringValue(value)) {
+			return
+		}
+	}
+}
+
+// AnyMap is a DataSource of Go values, e.g. decoded from JSON. The values are
+// converted with ValueOf.
+type AnyMap map[string]any
+
+// Lookup returns the value of the key.
+func (m AnyMap) Lookup(key string) (Value, bool) {
+	value, ok := m[key]
+	if !ok {
+		return Value{}, false
+	}
+	return ValueOf(value), true
+}
+
+// ScanPrefix calls fn with the keys starting with the prefix.
+func (m AnyMap) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	for key, value := range m {
+		if strings.HasPrefix(key, prefix) && !fn(key, ValueOf(value)) {
+			return
+		}
+	}
+}
+
+// URLValues is a DataSource of query parameters or form values. A key
+// returns its first value.
+type URLValues url.Values
+
+// Lookup returns the first value of the key.
+func (v URLValues) Lookup(key string) (Value, bool) {
+	values, ok := v[key]
+	if !ok || len(values) == 0 {
+		return Value{}, false
+	}
+	return StringValue(values[0]), true
+}
+
+// ScanPrefix calls fn with the keys starting with the prefix.
+func (v URLValues) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	for key, values := range v {
+		if strings.HasPrefix(key, prefix) && len(values) > 0 && !fn(key, StringValue(values[0])) {
+			return
+		}
+	}
+}
+
+// HTTPHeader is a DataSource of HTTP headers. Keys are case-insensitive and
+// return the first value of the header.
+type HTTPHeader http.Header
+
+// Lookup returns the first value of the header.
+func (h HTTPHeader) Lookup(key string) (Value, bool) {
+	values, ok := h[http.CanonicalHeaderKey(key)]
+	if !ok || len(values) == 0 {
+		return Value{}, false
+	}
+	return StringValue(values[0]), true
+}
+
+// ScanPrefix calls fn with the headers starting with the prefix, the prefix is case-insensitive.
+func (h HTTPHeader) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	for key, values := range h {
+		if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) && len(values) > 0 && !fn(key, StringValue(values[0])) {
+			return
+		}
+	}
+}
+
+// ValueOf converts a Go value. Strings, bools, integers, floats, json.Number,
+// time.Time and nil keep their kind, slices and arrays are lists, structs and
+// maps with string keys are objects and pointers are dereferenced. Other values
+// and types with a String method are formatted as text.
+func ValueOf(value any) Value {
+	switch v := value.(type) {
+	case nil:
+		return NullValue()
+	case Value:
+		return v
+	case string:
+		return StringValue(v)
+	case json.Number:
+		return numberLiteral(v.String())
+	case time.Time:
+		return TimeValue(v)
+	case map[string]any:
+		return ObjectValue(AnyMap(v))
+	case []any:
+		items := make([]Value, len(v))
+		for i, item := range v {
+			items[i] = ValueOf(item)
+		}
+		return ListValue(items)
+	case []byte:
+		return StringValue(string(v))
+	case bool:
+		return BoolValue(v)
+	case float64:
+		return NumberValue(v)
+	case float32:
+		return NumberValue(float64(v))
+	case int:
+		return NumberValue(float64(v))
+	case int8:
+		return NumberValue(float64(v))
+	case int16:
+		return NumberValue(float64(v))
+	case int32:
+		return NumberValue(float64(v))
+	case int64:
+		return NumberValue(float64(v))
+	case uint:
+		return NumberValue(float64(v))
+	case uint8:
+		return NumberValue(float64(v))
+	case uint16:
+		return NumberValue(float64(v))
+	case uint32:
+		return NumberValue(float64(v))
+	case uint64:
+		return NumberValue(float64(v))
+	}
+
+	rv := reflect.ValueOf(value)
+	if _, ok := value.(fmt.Stringer); ok && rv.Kind() != reflect.Struct && rv.Kind() != reflect.Pointer {
+		return StringValue(fmt.Sprint(value))
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return StringValue(rv.String())
+	case reflect.Bool:
+		return BoolValue(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return NumberValue(float64(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NumberValue(float64(rv.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return NumberValue(rv.Float())
+	case reflect.Struct:
+		return ObjectValue(Struct(value))
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return NullValue()
+		}
+		return ValueOf(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		items := make([]Value, rv.Len())
+		for i := range items {
+			items[i] = ValueOf(rv.Index(i).Interface())
+		}
+		return ListValue(items)
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return ObjectValue(reflectMap{rv})
+		}
+	default:
+		// other kinds are formatted as text
+	}
+
+	return StringValue(fmt.Sprint(value))
+}
+
+// reflectMap is a DataSource of a map with string keys and any values.
+type reflectMap struct {
+	value reflect.Value
+}
+
+func (m reflectMap) Lookup(key string) (Value, bool) {
+	field := m.value.MapIndex(reflect.ValueOf(key).Convert(m.value.Type().Key()))
+	if !field.IsValid() {
+		return Value{}, false
+	}
+	return ValueOf(field.Interface()), true
+}
+
+func (m reflectMap) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	iter := m.value.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		if strings.HasPrefix(key, prefix) && !fn(key, ValueOf(iter.Value().Interface())) {
+			return
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package simplequery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringMap(t *testing.T) {
+	t.Parallel()
+
+	data := StringMap{"a": "1"}
+
+	value, ok := data.Lookup("a")
+	assert.True(t, ok)
+	assert.Equal(t, StringValue("1"), value)
+
+	_, ok = data.Lookup("b")
+	assert.False(t, ok)
+}
+
+func TestAnyMap(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	number := 7
+
+	data := AnyMap{
+		"string": "abc",
+		"float":  1.5,
+		"int":    -3,
+		"uint":   uint8(4),
+		"bool":   true,
+		"nil":    nil,
+		"value":  NumberValue(2),
+		"other":  time.Duration(0),
+		"json":   json.Number("2.5"),
+		"time":   date,
+		"ptr":    &number,
+		"nilPtr": (*int)(nil),
+	}
+
+	testCases := []struct {
+		key   string
+		value Value
+	}{
+		{key: "string", value: StringValue("abc")},
+		{key: "float", value: NumberValue(1.5)},
+		{key: "int", value: NumberValue(-3)},
+		{key: "uint", value: NumberValue(4)},
+		{key: "bool", value: BoolValue(true)},
+		{key: "nil", value: NullValue()},
+		{key: "value", value: NumberValue(2)},
+		{key: "other", value: StringValue("0s")},
+		{key: "json", value: NumberValue(2.5)},
+		{key: "time", value: TimeValue(date)},
+		{key: "ptr", value: NumberValue(7)},
+		{key: "nilPtr", value: NullValue()},
+	}
+
+	for _, testCase := range testCases {
+		value, ok := data.Lookup(testCase.key)
+		assert.True(t, ok, testCase.key)
+		assert.Equal(t, testCase.value, value, testCase.key)
+	}
+
+	_, ok := data.Lookup("missing")
+	assert.False(t, ok)
+}
+
+func TestURLValues(t *testing.T) {
+	t.Parallel()
+
+	data := URLValues(url.Values{"a": {"1", "2"}, "empty": {}})
+
+	value, ok := data.Lookup("a")
+	assert.True(t, ok)
+	assert.Equal(t, StringValue("1"), value)
+
+	_, ok = data.Lookup("empty")
+	assert.False(t, ok)
+	_, ok = data.Lookup("b")
+	assert.False(t, ok)
+}
+
+func TestHTTPHeader(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Add("Content-Type", "text/plain")
+	header.Add("Content-Type", "text/html")
+	data := HTTPHeader(header)
+
+	value, ok := data.Lookup("content-type")
+	assert.True(t, ok)
+	assert.Equal(t, StringValue("text/plain"), value)
+
+	_, ok = data.Lookup("Accept")
+	assert.False(t, ok)
+}
+
+func TestMatchSource(t *testing.T) {
+	t.Parallel()
+
+	lookups := []string{}
+	data := DataSourceFunc(func(key string) (Value, bool) {
+		lookups = append(lookups, key)
+		switch key {
+		case "amount":
+			return NumberValue(120), true
+		case "region":
+			return StringValue("north"), true
+		}
+		return Value{}, false
+	})
+
+	ok, explanation, err := MatchSource("amount > 100 AND region = north", data)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []bool{true, true}, explanation.Details())
+	assert.Equal(t, []string{"amount", "region"}, lookups)
+
+	result, _, err := EvaluateSource("amount > 100 AND unknownKey = 1", data, WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+
+	_, _, err = MatchSource("amount >", data)
+	assert.Error(t, err)
+	_, _, err = EvaluateSource("amount >", data)
+	assert.Error(t, err)
+}
+
+func TestMatchSourceNull(t *testing.T) {
+	t.Parallel()
+
+	data := AnyMap{"deletedAt": nil, "name": ""}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "deletedAt", ok: true},
+		{query: "deletedAt = null", ok: true},
+		{query: "deletedAt != null", ok: false},
+		{query: "deletedAt > 1", ok: false},
+		{query: "deletedAt IS NULL", ok: true},
+		{query: "deletedAt IS MISSING", ok: false},
+		{query: "deletedAt IS EMPTY", ok: false},
+		{query: "deletedAt IS NOT EMPTY", ok: false},
+		{query: "name IS EMPTY AND name IS NOT NULL", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := MatchSource(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{}
+	err := json.Unmarshal([]byte(`{"amount": 120.5, "count": 3, "approved": true, "code": "007", "note": null}`), &data)
+	assert.NoError(t, err)
+	data["created"] = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		query   string
+		ok      bool
+		isError bool
+	}{
+		{query: "amount > 100", ok: true},
+		{query: "amount = 120.5", ok: true},
+		{query: "count = 3.0", ok: true},
+		{query: "approved = true", ok: true},
+		{query: "approved", ok: true},
+		{query: "code = 7", ok: false},
+		{query: "code = 007", ok: true},
+		{query: "code = '007'", ok: true},
+		{query: "note = NULL", ok: true},
+		{query: "note IS NULL", ok: true},
+		{query: "note IS MISSING", ok: false},
+		{query: "created > '2024-03-01'", ok: true},
+		{query: "created < '2024-03-01T13:00:00Z'", ok: true},
+		{query: "created = '2024-03-01T12:00:00Z'", ok: true},
+		{query: "amount + count > 123", ok: true},
+		{query: "created > 'soon'", isError: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := MatchAny(testCase.query, data)
+		if testCase.isError {
+			assert.Error(t, err, testCase.query)
+			continue
+		}
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	q, err := Compile("count >= 3")
+	assert.NoError(t, err)
+	ok, _, err := q.MatchAny(data)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,32 @@
+package simplequery
+
+import (
+	"fmt"
+)
+
+// EvalError is returned if a valid query fails on the data, e.g. on a division
+// by zero or a text in a numeric comparison. Span and Text point to the failing
+// part of the query, or of the source of the Macro.
+type EvalError struct {
+	Span  Span
+	Text  string
+	Macro string
+	Err   error
+}
+
+func newEvalError(source string, n node, err error) *EvalError {
+	s := n.span()
+	return &EvalError{Span: s, Text: source[s.Start:s.End], Err: err}
+}
+
+func (e *EvalError) Error() string {
+	if e.Macro != "" {
+		return fmt.Sprintf("evaluation of %s on %d of macro @%s failed: %s", e.Text, e.Span.Start+1, e.Macro, e.Err)
+	}
+	return fmt.Sprintf("evaluation of %s on %d failed: %s", e.Text, e.Span.Start+1, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *EvalError) Unwrap() error {
+	return e.Err
+}
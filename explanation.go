@@ -0,0 +1,128 @@
+package simplequery
+
+// NodeType is the type of an explained node of the query.
+type NodeType int
+
+const (
+	NodeAnd        NodeType = iota // a AND b
+	NodeOr                         // a OR b
+	NodeNot                        // !a
+	NodeGroup                      // (a)
+	NodeCompare                    // a = b or a custom operator
+	NodePredicate                  // a IS EMPTY or EXISTS a
+	NodeQuantifier                 // ANY items: a
+	NodeItem                       // an item of a quantifier
+	NodeWildcard                   // a condition with a wildcard key
+	NodeKey                        // a key
+	NodeLiteral                    // a text, number, bool or null
+	NodeCalc                       // a calculation
+	NodeCall                       // a function call
+	NodeMacro                      // @name
+)
+
+var nodeTypes = []string{
+	NodeAnd:        "and",
+	NodeOr:         "or",
+	NodeNot:        "not",
+	NodeGroup:      "group",
+	NodeCompare:    "compare",
+	NodePredicate:  "predicate",
+	NodeQuantifier: "quantifier",
+	NodeItem:       "item",
+	NodeWildcard:   "wildcard",
+	NodeKey:        "key",
+	NodeLiteral:    "literal",
+	NodeCalc:       "calc",
+	NodeCall:       "call",
+	NodeMacro:      "macro",
+}
+
+// String name of a node type
+func (t NodeType) String() string {
+	return nodeTypes[t]
+}
+
+// Explanation mirrors the query tree with the result of each node. Conditions
+// have a Result, operands of comparisons, calculations and calls have a Value.
+type Explanation struct {
+	Type NodeType
+	// Span of the node in the query and its Text.
+	Span Span
+	Text string
+	// Operator is AND, OR, NOT, the comparison or calculation operator, the
+	// predicate, the quantifier or the name of the function.
+	Operator string
+	// Key is the name of a key or a macro. For a wildcard key bound to a
+	// matching key, it is the matching key, for an item of a list its index.
+	Key string
+	// Value of an operand and whether it was found. A missing key, or an
+	// operand with a missing key, is not found.
+	Value Value
+	Found bool
+	// Condition reports whether the node is a condition with a Result.
+	Condition bool
+	Result    Truth
+	// Skipped reports whether the node was not evaluated, because the left
+	// side of AND or OR already decided the result. Its Result is UNKNOWN.
+	Skipped bool
+	// Err is the *EvalError of a failed node, e.g. a division by zero.
+	// The result of a failed node is UNKNOWN.
+	Err      error
+	Children []*Explanation
+}
+
+// Details returns the results of the conditions, the brackets and the items of
+// quantifiers in the order of the query. A negation of a condition is part of
+// the condition, UNKNOWN is false.
+func (x *Explanation) Details() []bool {
+	if x == nil {
+		return nil
+	}
+
+	details := []bool{}
+	x.details(&details)
+	return details
+}
+
+func (x *Explanation) details(details *[]bool) {
+	switch x.Type {
+	case NodeAnd, NodeOr:
+		for _, child := range x.Children {
+			child.details(details)
+		}
+	case NodeNot:
+		if x.Children[0].isLeaf() {
+			*details = append(*details, x.Result == True)
+			return
+		}
+		x.Children[0].details(details)
+	case NodeGroup:
+		x.Children[0].details(details)
+		*details = append(*details, x.Result == True)
+	case NodeQuantifier:
+		for _, item := range x.Children {
+			*details = append(*details, item.Result == True)
+		}
+		*details = append(*details, x.Result == True)
+	case NodeMacro:
+		if x.isLeaf() {
+			*details = append(*details, x.Result == True)
+			return
+		}
+		x.Children[0].details(details)
+	default:
+		*details = append(*details, x.Result == True)
+	}
+}
+
+// isLeaf reports whether the node is a single condition.
+func (x *Explanation) isLeaf() bool {
+	switch x.Type {
+	case NodeAnd, NodeOr, NodeNot, NodeGroup, NodeQuantifier:
+		return false
+	case NodeMacro:
+		return len(x.Children) == 0 || x.Children[0].isLeaf()
+	default:
+		return true
+	}
+}
@@ -0,0 +1,126 @@
+package simplequery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeTypeToString(t *testing.T) {
+	t.Parallel()
+
+	for nodeType := NodeAnd; nodeType <= NodeCall; nodeType++ {
+		assert.Greater(t, len(nodeType.String()), 0)
+	}
+}
+
+func TestExplanation(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"amount": "750", "region": "north"}
+
+	ok, x, err := Match("amount > 1000 OR !(region = north AND missingKey)", data)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	assert.Equal(t, NodeOr, x.Type)
+	assert.Equal(t, "OR", x.Operator)
+	assert.Equal(t, True, x.Result)
+	assert.Equal(t, Span{Start: 0, End: 49}, x.Span)
+	assert.Len(t, x.Children, 2)
+
+	compare := x.Children[0]
+	assert.Equal(t, NodeCompare, compare.Type)
+	assert.Equal(t, "amount > 1000", compare.Text)
+	assert.Equal(t, ">", compare.Operator)
+	assert.True(t, compare.Condition)
+	assert.Equal(t, False, compare.Result)
+
+	key := compare.Children[0]
+	assert.Equal(t, NodeKey, key.Type)
+	assert.Equal(t, "amount", key.Key)
+	assert.Equal(t, StringValue("750"), key.Value)
+	assert.True(t, key.Found)
+	assert.False(t, key.Condition)
+	assert.Equal(t, Span{Start: 0, End: 6}, key.Span)
+
+	literal := compare.Children[1]
+	assert.Equal(t, NodeLiteral, literal.Type)
+	assert.Equal(t, "1000", literal.Value.String())
+
+	not := x.Children[1]
+	assert.Equal(t, NodeNot, not.Type)
+	assert.Equal(t, True, not.Result)
+	group := not.Children[0]
+	assert.Equal(t, NodeGroup, group.Type)
+	assert.Equal(t, "(region = north AND missingKey)", group.Text)
+	assert.Equal(t, False, group.Result)
+
+	and := group.Children[0]
+	assert.Equal(t, NodeAnd, and.Type)
+	assert.Equal(t, True, and.Children[0].Result)
+	missing := and.Children[1]
+	assert.Equal(t, NodeKey, missing.Type)
+	assert.False(t, missing.Found)
+	assert.Equal(t, False, missing.Result)
+
+	assert.Equal(t, []bool{false, true, false, false}, x.Details())
+}
+
+func TestExplanationOperands(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{"net": 100, "tax": 19, "name": " Jane ", "items": []any{map[string]any{"qty": 3}}, "approval.bob": "ok"}
+
+	_, x, err := MatchAny("net + tax > 100 AND len(trim(name)) = 4 AND missingKey * 2 > 1", data)
+	assert.NoError(t, err)
+
+	calc := x.Children[0].Children[0].Children[0]
+	assert.Equal(t, NodeCalc, calc.Type)
+	assert.Equal(t, "+", calc.Operator)
+	assert.Equal(t, NumberValue(119), calc.Value)
+	assert.True(t, calc.Found)
+
+	call := x.Children[0].Children[1].Children[0]
+	assert.Equal(t, NodeCall, call.Type)
+	assert.Equal(t, "len", call.Operator)
+	assert.Equal(t, NumberValue(4), call.Value)
+	assert.Equal(t, "trim", call.Children[0].Operator)
+	assert.Equal(t, StringValue("Jane"), call.Children[0].Value)
+
+	missing := x.Children[1].Children[0]
+	assert.Equal(t, NodeCalc, missing.Type)
+	assert.False(t, missing.Found)
+	assert.False(t, missing.Children[0].Found)
+
+	_, x, err = MatchAny("ANY items: qty > 2", data)
+	assert.NoError(t, err)
+	assert.Equal(t, NodeQuantifier, x.Type)
+	assert.Equal(t, "ANY", x.Operator)
+	assert.Equal(t, "items", x.Key)
+	assert.Len(t, x.Children, 1)
+	assert.Equal(t, NodeItem, x.Children[0].Type)
+	assert.Equal(t, "items[0]", x.Children[0].Key)
+	assert.Equal(t, "qty > 2", x.Children[0].Text)
+	assert.Equal(t, True, x.Children[0].Result)
+	assert.Equal(t, NumberValue(3), x.Children[0].Children[0].Children[0].Value)
+
+	_, x, err = MatchAny("approval.* = ok AND EXISTS name", data)
+	assert.NoError(t, err)
+	wildcard := x.Children[0]
+	assert.Equal(t, NodeWildcard, wildcard.Type)
+	assert.Equal(t, "approval.*", wildcard.Key)
+	assert.Equal(t, "approval.bob", wildcard.Children[0].Children[0].Key)
+	predicate := x.Children[1]
+	assert.Equal(t, NodePredicate, predicate.Type)
+	assert.Equal(t, "EXISTS", predicate.Operator)
+	assert.Equal(t, "name", predicate.Key)
+
+	_, x, err = Match("a IS NOT EMPTY", map[string]string{"a": "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "IS NOT EMPTY", x.Operator)
+	assert.Equal(t, StringValue("x"), x.Value)
+
+	var empty *Explanation
+	assert.Nil(t, empty.Details())
+}
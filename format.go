@@ -0,0 +1,188 @@
+package simplequery
+
+import (
+	"strings"
+)
+
+// precedences of the nodes which are no binary operators
+const (
+	precedenceLogical = 1 // AND and OR bind the same
+	precedencePrefix  = 3 // NOT, quantifiers and EXISTS
+	precedenceUnary   = PrecedenceProduct + 10
+	precedencePrimary = precedenceUnary + 10
+)
+
+// Format prints the query in canonical form: upper case keywords, one space
+// around binary operators, an explicit AND between conditions, quoted texts
+// and only the brackets which are needed. The formatted query compiles to an
+// equivalent query with the same options, e.g. the same functions and library.
+// Values bound to placeholders are printed as literals, a time as text, lists
+// and objects can not be written as literal and are printed as text.
+func Format(q *Query) string {
+	var b strings.Builder
+	format(&b, q.root)
+	return b.String()
+}
+
+// String returns the query in canonical form, see Format.
+func (q *Query) String() string {
+	return Format(q)
+}
+
+func format(b *strings.Builder, n node) {
+	switch n := n.(type) {
+	case *binaryNode:
+		switch {
+		case n.op == AND || n.op == OR:
+			precedence := precedenceOf(n)
+			formatOperand(b, n.left, precedence)
+			b.WriteString(" " + n.op.String() + " ")
+			formatOperand(b, n.right, precedence+1)
+		case isArithmetic(n.op):
+			precedence := precedenceOf(n)
+			formatOperand(b, n.left, precedence)
+			b.WriteString(" " + n.op.String() + " ")
+			formatOperand(b, n.right, precedence+1)
+		default:
+			symbol := n.op.String()
+			if n.operator != nil {
+				symbol = n.symbol
+			}
+
+			precedence := precedenceOf(n)
+			formatOperand(b, n.left, precedence+1)
+			b.WriteString(" " + symbol + " ")
+
+			// a single word on the right side is a text, a key needs brackets
+			if _, ok := unwrap(n.right).(*keyNode); ok {
+				b.WriteString("(")
+				format(b, unwrap(n.right))
+				b.WriteString(")")
+				return
+			}
+			formatOperand(b, n.right, precedence+1)
+		}
+	case *notNode:
+		b.WriteString(tokens[N])
+		formatOperand(b, n.x, precedencePrefix)
+	case *negNode:
+		b.WriteString(tokens[MINUS])
+		formatOperand(b, n.x, precedenceUnary)
+	case *groupNode:
+		format(b, n.x)
+	case *quantifierNode:
+		b.WriteString(n.quantifier.String() + " ")
+		if n.wildcard {
+			formatOperand(b, n.x, PrecedenceCompare)
+			return
+		}
+		b.WriteString(n.key.name + tokens[COLON] + " ")
+		formatOperand(b, n.x, precedencePrefix)
+	case *isNode:
+		if n.predicate == EXISTS {
+			b.WriteString(tokens[EXISTS] + " " + n.key.name)
+			return
+		}
+		b.WriteString(n.key.name + " " + predicateText(n))
+	case *keyNode:
+		b.WriteString(n.name)
+	case *literalNode:
+		b.WriteString(formatValue(n.value))
+	case *callNode:
+		b.WriteString(n.name + "(")
+		for i, arg := range n.args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			formatOperand(b, arg, PrecedenceCompare+1)
+		}
+		b.WriteString(")")
+	case *paramNode:
+		if n.name == "" {
+			b.WriteString("?")
+			return
+		}
+		b.WriteString(":" + n.name)
+	case *macroNode:
+		b.WriteString("@" + n.macro.name)
+	}
+}
+
+// formatOperand prints the node in brackets if it binds weaker than the
+// precedence.
+func formatOperand(b *strings.Builder, n node, precedence int) {
+	n = unwrap(n)
+	if precedenceOf(n) >= precedence {
+		format(b, n)
+		return
+	}
+
+	b.WriteString("(")
+	format(b, n)
+	b.WriteString(")")
+}
+
+// unwrap returns the node in brackets, the brackets are printed where needed.
+func unwrap(n node) node {
+	for {
+		group, ok := n.(*groupNode)
+		if !ok {
+			return n
+		}
+		n = group.x
+	}
+}
+
+// precedenceOf returns how strong the node binds its operands.
+func precedenceOf(n node) int {
+	switch n := n.(type) {
+	case *binaryNode:
+		switch {
+		case n.op == AND || n.op == OR:
+			return precedenceLogical
+		case n.op == PLUS || n.op == MINUS:
+			return PrecedenceSum
+		case isArithmetic(n.op):
+			return PrecedenceProduct
+		case n.operator != nil:
+			return n.operator.Precedence
+		}
+		return PrecedenceCompare
+	case *notNode, *quantifierNode:
+		return precedencePrefix
+	case *isNode:
+		if n.predicate == EXISTS {
+			return precedencePrefix
+		}
+		return PrecedenceCompare
+	case *negNode:
+		return precedenceUnary
+	case *groupNode:
+		return precedenceOf(unwrap(n))
+	}
+
+	return precedencePrimary
+}
+
+// formatValue prints a literal.
+func formatValue(v Value) string {
+	switch v.Kind() {
+	case KindNull:
+		return tokens[NULL]
+	case KindBool:
+		if v.Truthy() {
+			return tokens[TRUE]
+		}
+		return tokens[FALSE]
+	case KindNumber:
+		return v.String()
+	default:
+		return quote(v.String())
+	}
+}
+
+// quote writes a text in double quotes with escapes for the lexer.
+func quote(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(text) + `"`
+}
@@ -0,0 +1,118 @@
+package simplequery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	registry := testOperators(t)
+	library := testLibrary(t)
+
+	testCases := []struct {
+		query  string
+		format string
+	}{
+		{query: "a and(b OR c )", format: "a AND (b OR c)"},
+		{query: "a b  c", format: "a AND b AND c"},
+		{query: "((a))", format: "a"},
+		{query: "(a AND b) AND c", format: "a AND b AND c"},
+		{query: "a AND (b AND c)", format: "a AND (b AND c)"},
+		{query: "(a OR b) OR c", format: "a OR b OR c"},
+		{query: "a OR (b AND c)", format: "a OR (b AND c)"},
+		{query: "a OR b AND c", format: "a OR b AND c"},
+		{query: "(a OR b) AND c", format: "a OR b AND c"},
+		{query: "not (a or b)", format: "!(a OR b)"},
+		{query: "! !a", format: "!!a"},
+		{query: "a= AND (b!=)", format: "a = \"\" AND b != \"\""},
+		{query: "!amount>5", format: "!amount > 5"},
+		{query: "amount>=1.50", format: "amount >= 1.50"},
+		{query: "region=north", format: "region = \"north\""},
+		{query: "region=(north)", format: "region = (north)"},
+		{query: "name='say \"hi\"\\n'", format: "name = \"say \\\"hi\\\"\\n\""},
+		{query: "a=12,34", format: "a = \"12,34\""},
+		{query: "a = true AND b=null", format: "a = TRUE AND b = NULL"},
+		{query: "(a + b) * c > (d - e) - f", format: "(a + b) * c > d - e - f"},
+		{query: "a - (b - c) > a / (b * c)", format: "a - (b - c) > a / (b * c)"},
+		{query: "-(a + 1) < - -b", format: "-(a + 1) < --b"},
+		{query: "(a > 1) = (b < 2)", format: "(a > 1) = (b < 2)"},
+		{query: "lower(name)=x AND max(a,b + 1)>(2)", format: "lower(name) = \"x\" AND max(a, b + 1) > 2"},
+		{query: "x is not empty and exists y", format: "x IS NOT EMPTY AND EXISTS y"},
+		{query: "any=none all", format: "any = \"none\" AND all"},
+		{query: "is IS NOT BLANK not=empty", format: "is IS NOT BLANK AND not = \"empty\""},
+		{query: "any items:(sku=a or qty>1) none tags: !x", format: "ANY items: (sku = \"a\" OR qty > 1) AND NONE tags: !x"},
+		{query: "all prices.* > 1", format: "ALL prices.* > 1"},
+		{query: "prices.* > 1 or x", format: "prices.* > 1 OR x"},
+		{query: "amount > :min AND b = ? AND ? > 1", format: "amount > :min AND b = ? AND ? > 1"},
+		{query: "tags@>b and (city geo.within germany) @> true", format: "tags @> \"b\" AND city geo.within \"germany\" @> TRUE"},
+		{query: "(tags @> b) geo.within x", format: "(tags @> \"b\") geo.within \"x\""},
+		{query: "@isEU and @gross>100", format: "@isEU AND @gross > 100"},
+	}
+
+	for _, testCase := range testCases {
+		q, err := Compile(testCase.query, WithOperators(registry), WithLibrary(library))
+		if !assert.NoError(t, err, testCase.query) {
+			continue
+		}
+		assert.Equal(t, testCase.format, Format(q), testCase.query)
+	}
+}
+
+func TestFormatEquivalent(t *testing.T) {
+	t.Parallel()
+
+	data := []map[string]any{
+		{"a": 1, "b": 2, "c": 3, "d": 10, "e": 4, "f": 1, "region": "north", "name": "Anna"},
+		{"a": 0, "b": "", "c": nil, "region": "south", "items": []any{map[string]any{"sku": "a"}}},
+		{},
+	}
+
+	queries := []string{
+		"a and(b OR c )",
+		"!(a OR b) c",
+		"(a + b) * c > (d - e) - f",
+		"a - (b - c) > a / (b * c) OR -(a + 1) < - -b",
+		"(a > 1) = (b < 2)",
+		"region=(north) OR region=north",
+		"lower(name) = anna AND len(name) > 3",
+		"c IS NULL AND b IS NOT EMPTY OR EXISTS items",
+		"ANY items: sku = a AND a = 0",
+		"a = ? OR b > :x",
+	}
+
+	for _, query := range queries {
+		q, err := Compile(query)
+		assert.NoError(t, err, query)
+
+		formatted, err := Compile(Format(q))
+		if !assert.NoError(t, err, Format(q)) {
+			continue
+		}
+		assert.Equal(t, Format(q), Format(formatted), query)
+		assert.Equal(t, Format(q), q.String(), query)
+
+		for _, values := range data {
+			expected, _, expectedErr := q.EvaluateSource(AnyMap(values), WithUnknown())
+			result, _, err := formatted.EvaluateSource(AnyMap(values), WithUnknown())
+			assert.Equal(t, expected, result, query)
+			assert.Equal(t, expectedErr == nil, err == nil, query)
+		}
+	}
+}
+
+func TestFormatBound(t *testing.T) {
+	t.Parallel()
+
+	q, err := Compile("a = ? AND b > ? AND c = ? AND d = ?")
+	assert.NoError(t, err)
+
+	bound, err := q.Bind(nil, "x \"y\"", -5, nil, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "a = \"x \\\"y\\\"\" AND b > -5 AND c = NULL AND d = FALSE", Format(bound))
+
+	_, err = Compile(Format(bound))
+	assert.NoError(t, err)
+}
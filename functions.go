@@ -0,0 +1,195 @@
+package simplequery
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// KindAny accepts an argument of any kind in a function signature.
+const KindAny Kind = -1
+
+// Function can be called from a query. The arguments are checked against the
+// parameters when the query is compiled and converted to the parameter kinds
+// before Call, so a KindNumber argument is always a number and a KindTime
+// argument always a time.
+type Function struct {
+	// Params are the kinds of the arguments.
+	Params []Kind
+	// Variadic allows to repeat the last parameter any number of times.
+	Variadic bool
+	// Result is the kind of the returned value. A function with a KindBool or
+	// KindAny result can be used as condition.
+	Result Kind
+	// Call calculates the result. An error is returned as *EvalError.
+	Call func(args []Value) (Value, error)
+	// CallContext calculates the result with the context of MatchContext,
+	// e.g. to query a store. It is used instead of Call if it is set.
+	CallContext func(ctx context.Context, args []Value) (Value, error)
+}
+
+// call calls CallContext or Call.
+func (fn *Function) call(ctx context.Context, args []Value) (Value, error) {
+	if fn.CallContext != nil {
+		return fn.CallContext(ctx, args)
+	}
+	return fn.Call(args)
+}
+
+// param returns the kind of the i-th argument.
+func (fn *Function) param(i int) Kind {
+	if fn.Variadic && i >= len(fn.Params)-1 {
+		return fn.Params[len(fn.Params)-1]
+	}
+	return fn.Params[i]
+}
+
+// accepts reports whether the function can be called with n arguments.
+func (fn *Function) accepts(n int) bool {
+	if fn.Variadic {
+		return n >= len(fn.Params)-1
+	}
+	return n == len(fn.Params)
+}
+
+// FunctionRegistry is a set of functions which can be used in queries.
+type FunctionRegistry struct {
+	functions map[string]*Function
+}
+
+// NewFunctionRegistry creates a registry with the built-in functions.
+func NewFunctionRegistry() *FunctionRegistry {
+	r := &FunctionRegistry{functions: map[string]*Function{}}
+	for name, fn := range builtins {
+		r.functions[name] = fn
+	}
+
+	return r
+}
+
+// Register adds a function to the registry. The name must consist of letters,
+// must not be a keyword and must not be registered yet.
+func (r *FunctionRegistry) Register(name string, fn Function) error {
+	if name == "" || strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) }) >= 0 {
+		return fmt.Errorf("invalid function name %q", name)
+	}
+	if _, ok := keywords[strings.ToUpper(name)]; ok {
+		return fmt.Errorf("function name %q is a keyword", name)
+	}
+	if _, ok := r.functions[name]; ok {
+		return fmt.Errorf("function %q is already registered", name)
+	}
+	if fn.Call == nil && fn.CallContext == nil {
+		return fmt.Errorf("function %q has no call", name)
+	}
+	if fn.Variadic && len(fn.Params) == 0 {
+		return fmt.Errorf("variadic function %q has no parameters", name)
+	}
+	for _, kind := range fn.Params {
+		if kind != KindAny && kind != KindString && kind != KindNumber && kind != KindBool && kind != KindTime {
+			return fmt.Errorf("function %q has an invalid parameter kind %s", name, kind.String())
+		}
+	}
+	if fn.Result != KindAny && (fn.Result < 0 || int(fn.Result) >= len(kinds)) {
+		return fmt.Errorf("function %q has an invalid result kind %s", name, fn.Result.String())
+	}
+
+	r.functions[name] = &fn
+	return nil
+}
+
+// lookup returns the function of the name, a nil registry has the built-in functions.
+func (r *FunctionRegistry) lookup(name string) (*Function, bool) {
+	if r == nil {
+		r = defaultFunctions
+	}
+
+	fn, ok := r.functions[name]
+	return fn, ok
+}
+
+// convert an argument to the kind of the parameter.
+func convert(value Value, kind Kind) (Value, error) {
+	switch kind {
+	case KindString:
+		return StringValue(value.String()), nil
+	case KindNumber:
+		number, err := value.Number()
+		if err != nil {
+			return Value{}, err
+		}
+		return NumberValue(number), nil
+	case KindBool:
+		return BoolValue(value.Truthy()), nil
+	case KindTime:
+		t, err := value.Time()
+		if err != nil {
+			return Value{}, err
+		}
+		return TimeValue(t), nil
+	default:
+		return value, nil
+	}
+}
+
+// builtins are the functions which are available in every query.
+var builtins = map[string]*Function{
+	"len": {Params: []Kind{KindString}, Result: KindNumber, Call: func(args []Value) (Value, error) {
+		return NumberValue(float64(utf8.RuneCountInString(args[0].String()))), nil
+	}},
+	"lower": {Params: []Kind{KindString}, Result: KindString, Call: stringFunction(strings.ToLower)},
+	"upper": {Params: []Kind{KindString}, Result: KindString, Call: stringFunction(strings.ToUpper)},
+	"trim":  {Params: []Kind{KindString}, Result: KindString, Call: stringFunction(strings.TrimSpace)},
+
+	"contains":   {Params: []Kind{KindString, KindString}, Result: KindBool, Call: textFunction(strings.Contains)},
+	"startsWith": {Params: []Kind{KindString, KindString}, Result: KindBool, Call: textFunction(strings.HasPrefix)},
+	"endsWith":   {Params: []Kind{KindString, KindString}, Result: KindBool, Call: textFunction(strings.HasSuffix)},
+
+	"abs":   {Params: []Kind{KindNumber}, Result: KindNumber, Call: numberFunction(math.Abs)},
+	"round": {Params: []Kind{KindNumber}, Result: KindNumber, Call: numberFunction(math.Round)},
+	"floor": {Params: []Kind{KindNumber}, Result: KindNumber, Call: numberFunction(math.Floor)},
+	"ceil":  {Params: []Kind{KindNumber}, Result: KindNumber, Call: numberFunction(math.Ceil)},
+	"min":   {Params: []Kind{KindNumber, KindNumber}, Result: KindNumber, Call: pairFunction(math.Min)},
+	"max":   {Params: []Kind{KindNumber, KindNumber}, Result: KindNumber, Call: pairFunction(math.Max)},
+}
+
+var defaultFunctions = &FunctionRegistry{functions: builtins}
+
+func stringFunction(fn func(string) string) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		return StringValue(fn(args[0].String())), nil
+	}
+}
+
+func textFunction(fn func(string, string) bool) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		return BoolValue(fn(args[0].String(), args[1].String())), nil
+	}
+}
+
+func numberFunction(fn func(float64) float64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		x, err := args[0].Number()
+		if err != nil {
+			return Value{}, err
+		}
+		return NumberValue(fn(x)), nil
+	}
+}
+
+func pairFunction(fn func(float64, float64) float64) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		x, err := args[0].Number()
+		if err != nil {
+			return Value{}, err
+		}
+		y, err := args[1].Number()
+		if err != nil {
+			return Value{}, err
+		}
+		return NumberValue(fn(x, y)), nil
+	}
+}
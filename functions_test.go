@@ -0,0 +1,261 @@
+package simplequery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltins(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		args   []Value
+		result Value
+	}{
+		{name: "len", args: []Value{StringValue("äbc")}, result: NumberValue(3)},
+		{name: "len", args: []Value{NumberValue(12.5)}, result: NumberValue(4)},
+		{name: "lower", args: []Value{StringValue("DE")}, result: StringValue("de")},
+		{name: "upper", args: []Value{StringValue("de")}, result: StringValue("DE")},
+		{name: "trim", args: []Value{StringValue(" de\t")}, result: StringValue("de")},
+		{name: "contains", args: []Value{StringValue("abc"), StringValue("b")}, result: BoolValue(true)},
+		{name: "startsWith", args: []Value{StringValue("abc"), StringValue("b")}, result: BoolValue(false)},
+		{name: "endsWith", args: []Value{StringValue("abc"), StringValue("bc")}, result: BoolValue(true)},
+		{name: "abs", args: []Value{StringValue("-2.5")}, result: NumberValue(2.5)},
+		{name: "round", args: []Value{NumberValue(2.5)}, result: NumberValue(3)},
+		{name: "floor", args: []Value{NumberValue(2.5)}, result: NumberValue(2)},
+		{name: "ceil", args: []Value{NumberValue(2.1)}, result: NumberValue(3)},
+		{name: "min", args: []Value{NumberValue(2), StringValue("1")}, result: NumberValue(1)},
+		{name: "max", args: []Value{NumberValue(2), StringValue("1")}, result: NumberValue(2)},
+	}
+
+	for _, testCase := range testCases {
+		fn := builtins[testCase.name]
+		if !assert.NotNil(t, fn, testCase.name) {
+			continue
+		}
+		assert.Len(t, testCase.args, len(fn.Params), testCase.name)
+
+		result, err := fn.Call(testCase.args)
+		assert.NoError(t, err, testCase.name)
+		assert.Equal(t, testCase.result, result, testCase.name)
+		assert.Equal(t, fn.Result, result.Kind(), testCase.name)
+	}
+
+	for _, name := range []string{"abs", "round", "floor", "ceil"} {
+		_, err := builtins[name].Call([]Value{StringValue("abc")})
+		assert.Error(t, err, name)
+	}
+	for _, name := range []string{"min", "max"} {
+		_, err := builtins[name].Call([]Value{StringValue("abc"), NumberValue(1)})
+		assert.Error(t, err, name)
+		_, err = builtins[name].Call([]Value{NumberValue(1), StringValue("abc")})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestMatchFunctions(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"comment": "Hello", "country": "DE", "amount": "-12.5", "spaces": " "}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "len(comment) > 0", ok: true},
+		{query: "len(trim(spaces)) = 0", ok: true},
+		{query: "lower(country) = \"de\"", ok: true},
+		{query: "lower(country) = 'de'", ok: true},
+		{query: "\"DE\" = upper(country)", ok: true},
+		{query: "lower(country) = upper(country)", ok: false},
+		{query: "abs(amount) = 12.5", ok: true},
+		{query: "abs(amount) + 1 > 13 AND max(amount, 0) = 0", ok: true},
+		{query: "max(1,2) = 2 AND max(1,amount) = 1", ok: true},
+		{query: "round(abs(amount) * 2) = 25", ok: true},
+		{query: "contains(comment, \"ell\")", ok: true},
+		{query: "!startsWith(comment, 'x') AND endsWith(comment, 'lo')", ok: true},
+		{query: "contains(comment, \"ell\") = false", ok: false},
+		{query: "len(missingKey) > 0", ok: false},
+		{query: "!len(missingKey) > 0", ok: true},
+		{query: "contains(missingKey, \"a\")", ok: false},
+		{query: "country (comment)", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	result, _, err := Evaluate("contains(missingKey, \"a\")", data, WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+}
+
+func TestMatchFunctionErrors(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"comment": "Hello"}
+
+	for _, query := range []string{
+		"unknown(comment) > 1",
+		"len() > 1",
+		"len(comment, comment) > 1",
+		"max(1) > 1",
+		"len(comment",
+		"len(comment comment) > 1",
+		"len(comment > 1)",
+		"len(comment)",
+		"contains(comment, \"a\") > 1",
+		"contains(comment, \"a\") + 1 = 2",
+		"len(contains(comment, \"a\") AND comment) > 1",
+		"comment = \"abc",
+	} {
+		_, _, err := Match(query, data)
+		assert.Error(t, err, query)
+	}
+
+	_, _, err := Match("abs(comment) > 1", data)
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "abs(comment)", evalErr.Text)
+		assert.Equal(t, Span{0, 12}, evalErr.Span)
+	}
+}
+
+func TestFunctionRegistry(t *testing.T) {
+	t.Parallel()
+
+	errClosed := errors.New("calendar closed")
+	registry := NewFunctionRegistry()
+
+	err := registry.Register("isBusinessDay", Function{
+		Params: []Kind{KindString},
+		Result: KindBool,
+		Call: func(args []Value) (Value, error) {
+			switch args[0].String() {
+			case "2026-10-17", "2026-10-18":
+				return BoolValue(false), nil
+			case "":
+				return Value{}, errClosed
+			}
+			return BoolValue(true), nil
+		},
+	})
+	assert.NoError(t, err)
+
+	err = registry.Register("inRegion", Function{
+		Params: []Kind{KindNumber, KindString},
+		Result: KindBool,
+		Call: func(args []Value) (Value, error) {
+			zip, _ := args[0].Number()
+			return BoolValue(args[1].String() == "north" && zip >= 20000 && zip < 30000), nil
+		},
+	})
+	assert.NoError(t, err)
+
+	err = registry.Register("join", Function{
+		Params:   []Kind{KindString, KindAny},
+		Variadic: true,
+		Result:   KindString,
+		Call: func(args []Value) (Value, error) {
+			parts := []string{}
+			for _, arg := range args[1:] {
+				parts = append(parts, arg.String())
+			}
+			return StringValue(strings.Join(parts, args[0].String())), nil
+		},
+	})
+	assert.NoError(t, err)
+
+	data := map[string]string{"dueDate": "2026-10-19", "weekend": "2026-10-18", "noDate": "", "zip": "20095", "city": "Hamburg"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "isBusinessDay(dueDate)", ok: true},
+		{query: "isBusinessDay(weekend)", ok: false},
+		{query: "!isBusinessDay(weekend) AND inRegion(zip, \"north\")", ok: true},
+		{query: "inRegion(zip, north)", ok: false},
+		{query: "inRegion(10000, \"north\")", ok: false},
+		{query: "join(\"-\", city, zip, 1) = \"Hamburg-20095-1\"", ok: true},
+		{query: "join(\"-\") = \"\"", ok: true},
+		{query: "len(city) = 7", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data, WithFunctions(registry))
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	q, err := Compile("isBusinessDay(dueDate)", WithFunctions(registry))
+	assert.NoError(t, err)
+	ok, _, err := q.Match(data)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// user functions are unknown without the registry
+	_, err = Compile("isBusinessDay(dueDate)")
+	assert.Error(t, err)
+	_, err = Compile("isBusinessDay(dueDate)", WithFunctions(nil))
+	assert.Error(t, err)
+	_, err = Compile("len(city) > 0", WithFunctions(nil))
+	assert.NoError(t, err)
+
+	for _, query := range []string{
+		"inRegion(zip)",
+		"inRegion(\"abc\", \"north\")",
+		"inRegion(true, \"north\")",
+		"inRegion(contains(city, \"a\"), \"north\")",
+		"join()",
+		"isBusinessDay(dueDate) > 1",
+	} {
+		_, err := Compile(query, WithFunctions(registry))
+		assert.Error(t, err, query)
+	}
+
+	_, _, err = Match("city AND isBusinessDay(noDate)", data, WithFunctions(registry))
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.ErrorIs(t, err, errClosed)
+		assert.Equal(t, "isBusinessDay(noDate)", evalErr.Text)
+		assert.Equal(t, Span{9, 30}, evalErr.Span)
+	}
+
+	_, _, err = Match("inRegion(city, \"north\")", data, WithFunctions(registry))
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "inRegion(city, \"north\")", evalErr.Text)
+	}
+}
+
+func TestFunctionRegistryRegister(t *testing.T) {
+	t.Parallel()
+
+	call := func(args []Value) (Value, error) { return NullValue(), nil }
+	registry := NewFunctionRegistry()
+
+	assert.NoError(t, registry.Register("custom", Function{Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("custom", Function{Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("len", Function{Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("", Function{Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("is_ok", Function{Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("and", Function{Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("noCall", Function{Result: KindNumber}))
+	assert.Error(t, registry.Register("noParams", Function{Variadic: true, Result: KindNumber, Call: call}))
+	assert.Error(t, registry.Register("nullParam", Function{Params: []Kind{KindNull}, Result: KindNumber, Call: call}))
+	assert.EqualError(t, registry.Register("badParam", Function{Params: []Kind{Kind(42)}, Result: KindNumber, Call: call}), `function "badParam" has an invalid parameter kind kind(42)`)
+	assert.EqualError(t, registry.Register("badResult", Function{Result: Kind(42), Call: call}), `function "badResult" has an invalid result kind kind(42)`)
+	assert.NoError(t, registry.Register("anyResult", Function{Result: KindAny, Call: call}))
+
+	// registering into a new registry does not change the built-in functions
+	_, ok := defaultFunctions.lookup("custom")
+	assert.False(t, ok)
+	_, ok = NewFunctionRegistry().lookup("custom")
+	assert.False(t, ok)
+}
@@ -0,0 +1,163 @@
+package simplequery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MatchJSON matches the input like Match against a JSON object.
+func MatchJSON(input string, data []byte, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	q, err := Compile(input, opts...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return q.MatchJSON(data, opts...)
+}
+
+// MatchJSON matches the compiled query against a JSON object. Only the values
+// of the keys used in the query are decoded, the rest of the document is
+// skipped. If a key is repeated, the first value is used.
+func (q *Query) MatchJSON(data []byte, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	values, err := decodeKeys(data, q.keys)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return q.MatchSource(AnyMap(values), opts...)
+}
+
+// decodeKeys reads the values of the keys from the top level of a JSON object.
+// A wildcard key reads all keys starting with its prefix.
+func decodeKeys(data []byte, keys map[string]bool) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	prefixes := []string{}
+	for key := range keys {
+		if isWildcard(key) {
+			prefixes = append(prefixes, strings.TrimSuffix(key, "*"))
+		}
+	}
+
+	values := map[string]any{}
+	for (len(values) < len(keys) || len(prefixes) > 0) && dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON document: %w", err)
+		}
+		key := token.(string)
+
+		if _, ok := values[key]; !isReferenced(key, keys, prefixes) || ok {
+			if err := skipValue(dec); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid JSON document: %w", err)
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// isReferenced reports whether the key is used in the query or matches a wildcard key.
+func isReferenced(key string, keys map[string]bool, prefixes []string) bool {
+	if keys[key] {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("invalid JSON document: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("invalid JSON document: %v is no object", token)
+	}
+
+	return nil
+}
+
+// skipValue reads the tokens of the next value without decoding it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return errors.New("invalid JSON document: unexpected end")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON document: %w", err)
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// referencedKeys returns the keys of the data which are used in the query,
+// for a path also its first segment. Keys in a quantifier over a list refer
+// to the items.
+func referencedKeys(n node, keys map[string]bool) {
+	add := func(key string) {
+		keys[key] = true
+		if isPath(key) {
+			keys[splitPath(key)[0]] = true
+		}
+	}
+
+	switch n := n.(type) {
+	case *keyNode:
+		add(n.name)
+	case *isNode:
+		add(n.key.name)
+	case *quantifierNode:
+		add(n.key.name)
+		if n.wildcard {
+			referencedKeys(n.x, keys)
+		}
+	case *binaryNode:
+		referencedKeys(n.left, keys)
+		referencedKeys(n.right, keys)
+	case *notNode:
+		referencedKeys(n.x, keys)
+	case *negNode:
+		referencedKeys(n.x, keys)
+	case *groupNode:
+		referencedKeys(n.x, keys)
+	case *callNode:
+		for _, arg := range n.args {
+			referencedKeys(arg, keys)
+		}
+	case *macroNode:
+		referencedKeys(n.macro.root, keys)
+	}
+}
@@ -0,0 +1,73 @@
+package simplequery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchJSON(t *testing.T) {
+	t.Parallel()
+
+	document := []byte(`{
+		"event": "order.created",
+		"payload": {"large": [1, 2, {"nested": [3, "]"]}], "text": "}"},
+		"amount": 120.50,
+		"customer": {"address": {"country": "DE"}},
+		"items": [{"sku": "X1", "qty": 3}],
+		"approved": true,
+		"note": null,
+		"flat.key": "flat"
+	}`)
+
+	testCases := []struct {
+		query   string
+		ok      bool
+		details []bool
+	}{
+		{query: "amount > 100", ok: true, details: []bool{true}},
+		{query: `event = "order.created" AND approved`, ok: true, details: []bool{true, true}},
+		{query: "customer.address.country = DE", ok: true, details: []bool{true}},
+		{query: `ANY items: sku = "X1"`, ok: true, details: []bool{true, true}},
+		{query: "note IS NULL AND note IS NOT MISSING", ok: true, details: []bool{true, true}},
+		{query: "flat.key = flat", ok: true, details: []bool{true}},
+		{query: "len(event) = 13", ok: true, details: []bool{true}},
+		{query: "unknownKey", ok: false, details: []bool{false}},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := MatchJSON(testCase.query, document)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.query)
+	}
+
+	for _, document := range []string{`[1, 2]`, `"text"`, ``, `{"amount": `, `{"payload": [1, 2`, `{"amount" 1}`} {
+		_, _, err := MatchJSON("amount > 1", []byte(document))
+		assert.Error(t, err, document)
+	}
+
+	_, _, err := MatchJSON("amount >", document)
+	assert.Error(t, err)
+}
+
+func TestDecodeKeys(t *testing.T) {
+	t.Parallel()
+
+	q, err := Compile("a.b > 1 AND !c AND len(d) > 0 AND e IS EMPTY AND ANY f: g")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"a.b": true, "a": true, "c": true, "d": true, "e": true, "f": true}, q.keys)
+
+	values, err := decodeKeys([]byte(`{"x": {"a": [1]}, "a": {"b": 2}, "c": 1, "a": 3, "g": 4}`), q.keys)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": map[string]any{"b": json.Number("2")}, "c": json.Number("1")}, values)
+
+	// the scan stops after all keys are found, a path key may exist as it is
+	values, err = decodeKeys([]byte(`{"c": 1, "d": "x", "e": "", "f": [], "a": {}, "a.b": 1, "g": invalid`), q.keys)
+	assert.NoError(t, err)
+	assert.Len(t, values, 6)
+
+	_, err = decodeKeys([]byte(`{"c": 1, "d": "x", "e": "", "f": [], "a": {}, "g": invalid`), q.keys)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package simplequery
 
 import (
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -13,6 +14,9 @@ const (
 	ILLEGAL
 	IDENT
 	NUMBER
+	STRING
+	PARAM // :name or ?
+	MACRO // @name
 
 	// Infix ops
 	EQ  // =
@@ -25,9 +29,38 @@ const (
 
 	BRACKET_LEFT  // (
 	BRACKET_RIGHT // )
+	COMMA         // ,
+	COLON         // :
+
+	// Arithmetic ops
+	PLUS  // +
+	MINUS // -
+	MUL   // *
+	DIV   // /
+	MOD   // %
 
 	OR  // or
 	AND // and
+
+	OPERATOR // registered operator
+
+	// Literals
+	TRUE  // true
+	FALSE // false
+	NULL  // null
+
+	// Predicates
+	IS      // is
+	NOT     // not
+	EMPTY   // empty
+	MISSING // missing
+	BLANK   // blank
+	EXISTS  // exists
+
+	// Quantifiers
+	ANY  // any
+	ALL  // all
+	NONE // none
 )
 
 var tokens = []string{
@@ -35,6 +68,9 @@ var tokens = []string{
 	ILLEGAL: "ILLEGAL",
 	IDENT:   "IDENT",
 	NUMBER:  "NUMBER",
+	STRING:  "STRING",
+	PARAM:   "PARAM",
+	MACRO:   "MACRO",
 
 	// Infix ops
 	EQ:  "=",
@@ -47,9 +83,54 @@ var tokens = []string{
 
 	BRACKET_LEFT:  "(",
 	BRACKET_RIGHT: ")",
+	COMMA:         ",",
+	COLON:         ":",
+
+	PLUS:  "+",
+	MINUS: "-",
+	MUL:   "*",
+	DIV:   "/",
+	MOD:   "%",
 
 	AND: "AND",
 	OR:  "OR",
+
+	OPERATOR: "OPERATOR",
+
+	TRUE:  "TRUE",
+	FALSE: "FALSE",
+	NULL:  "NULL",
+
+	IS:      "IS",
+	NOT:     "NOT",
+	EMPTY:   "EMPTY",
+	MISSING: "MISSING",
+	BLANK:   "BLANK",
+	EXISTS:  "EXISTS",
+
+	ANY:  "ANY",
+	ALL:  "ALL",
+	NONE: "NONE",
+}
+
+// keywords are matched case-insensitively against whole identifiers.
+var keywords = map[string]Token{
+	"AND":   AND,
+	"OR":    OR,
+	"TRUE":  TRUE,
+	"FALSE": FALSE,
+	"NULL":  NULL,
+
+	"IS":      IS,
+	"NOT":     NOT,
+	"EMPTY":   EMPTY,
+	"MISSING": MISSING,
+	"BLANK":   BLANK,
+	"EXISTS":  EXISTS,
+
+	"ANY":  ANY,
+	"ALL":  ALL,
+	"NONE": NONE,
 }
 
 // String name of a token
@@ -59,8 +140,15 @@ func (t Token) String() string {
 
 // Lexer breaks down the input as tokens
 type Lexer struct {
-	input string
-	pos   int
+	input     string
+	pos       int
+	start     int
+	operators *OperatorRegistry
+	library   *Library
+	// brackets holds for each open bracket whether it starts the arguments
+	// of a call, which is a bracket right behind a name
+	brackets []bool
+	last     Token
 }
 
 // NewLexer create a lexer
@@ -81,6 +169,14 @@ func (l *Lexer) next() rune {
 	return r
 }
 
+func (l *Lexer) peek() rune {
+	r := l.next()
+	if r != EOF {
+		l.backup()
+	}
+	return r
+}
+
 func (l *Lexer) backup() {
 	if l.pos > 0 {
 		_, w := utf8.DecodeRuneInString(l.input[:l.pos])
@@ -90,7 +186,43 @@ func (l *Lexer) backup() {
 
 // Lex returns the next token, the position and the content.
 func (l *Lexer) Lex() (position int, token Token, text string) {
+	end := l.pos
+	position, token, text = l.lex()
+
+	switch token {
+	case BRACKET_LEFT:
+		l.brackets = append(l.brackets, (l.last == IDENT || isWord(l.last)) && l.start == end)
+	case BRACKET_RIGHT:
+		if len(l.brackets) > 0 {
+			l.brackets = l.brackets[:len(l.brackets)-1]
+		}
+	}
+	l.last = token
+
+	return position, token, text
+}
+
+// inCall reports whether the arguments of a call are read.
+func (l *Lexer) inCall() bool {
+	return len(l.brackets) > 0 && l.brackets[len(l.brackets)-1]
+}
+
+func (l *Lexer) lex() (position int, token Token, text string) {
 	for {
+		l.start = l.pos
+
+		// with a library @name references a macro instead of an operator
+		if l.library != nil {
+			if name, ok := l.lexMacro(); ok {
+				return l.start + 1, MACRO, name
+			}
+		}
+
+		if symbol, ok := l.lexOperator(); ok {
+			_, w := utf8.DecodeRuneInString(symbol)
+			return l.start + w, OPERATOR, symbol
+		}
+
 		switch r := l.next(); {
 		case r == EOF:
 			return l.pos, EOF, ""
@@ -100,30 +232,53 @@ func (l *Lexer) Lex() (position int, token Token, text string) {
 			return l.pos, BRACKET_LEFT, "("
 		case r == ')':
 			return l.pos, BRACKET_RIGHT, ")"
+		case r == ',':
+			return l.pos, COMMA, ","
+		case r == ':' && unicode.IsLetter(l.peek()):
+			startPos := l.pos
+			return startPos, PARAM, l.lexParam()
+		case r == ':':
+			return l.pos, COLON, ":"
+		case r == '?':
+			return l.pos, PARAM, "?"
+		case r == '"' || r == '\'':
+			startPos := l.pos
+			lit, ok := l.lexString(r)
+			if !ok {
+				return startPos, ILLEGAL, l.input[l.start:l.pos]
+			}
+			return startPos, STRING, lit
+		case r == '+':
+			return l.pos, PLUS, "+"
+		case r == '-':
+			return l.pos, MINUS, "-"
+		case r == '*':
+			return l.pos, MUL, "*"
+		case r == '/':
+			return l.pos, DIV, "/"
+		case r == '%':
+			return l.pos, MOD, "%"
 		case r == '=':
 			return l.pos, EQ, "="
 		case r == '>':
-			startPos := l.pos
-			if l.next() == '=' {
+			if l.peek() == '=' {
+				startPos := l.pos
+				l.next()
 				return startPos, GTE, ">="
-			} else {
-				l.backup()
 			}
 			return l.pos, GT, ">"
 		case r == '<':
-			startPos := l.pos
-			if l.next() == '=' {
+			if l.peek() == '=' {
+				startPos := l.pos
+				l.next()
 				return startPos, LTE, "<="
-			} else {
-				l.backup()
 			}
 			return l.pos, LT, "<"
 		case r == '!':
 			startPos := l.pos
-			if l.next() == '=' {
+			if l.peek() == '=' {
+				l.next()
 				return startPos, NE, "!="
-			} else {
-				l.backup()
 			}
 			return startPos, N, "!"
 		case unicode.IsDigit(r):
@@ -133,31 +288,11 @@ func (l *Lexer) Lex() (position int, token Token, text string) {
 			return startPos, NUMBER, lit
 		case unicode.IsLetter(r):
 			startPos := l.pos
-			switch r {
-			case 'A', 'a':
-				nextN := l.next()
-				if nextN == 'N' || nextN == 'n' {
-					nextD := l.next()
-					if nextD == 'D' || nextD == 'd' {
-						return startPos, AND, "AND"
-					} else {
-						l.backup()
-						l.backup()
-					}
-				} else {
-					l.backup()
-				}
-			case 'O', 'o':
-				nextR := l.next()
-				if nextR == 'R' || nextR == 'r' {
-					return startPos, OR, "OR"
-				} else {
-					l.backup()
-				}
-			}
-
 			l.backup()
 			lit := l.lexIdent()
+			if tok, ok := keywords[strings.ToUpper(lit)]; ok {
+				return startPos, tok, tokens[tok]
+			}
 			return startPos, IDENT, lit
 		default:
 			return l.pos, ILLEGAL, string(r)
@@ -165,6 +300,11 @@ func (l *Lexer) Lex() (position int, token Token, text string) {
 	}
 }
 
+// span of the last token in the input.
+func (l *Lexer) span() Span {
+	return Span{Start: l.start, End: l.pos}
+}
+
 func (l *Lexer) lexNumber() string {
 	var lit string
 	for {
@@ -176,6 +316,12 @@ func (l *Lexer) lexNumber() string {
 		case r == '.':
 			lit = lit + string(r)
 		case r == ',':
+			// a comma between digits is part of a number like 12,34, in the
+			// arguments of a call it separates the arguments
+			if l.inCall() || !unicode.IsDigit(l.peek()) {
+				l.backup()
+				return lit
+			}
 			lit = lit + string(r)
 		default:
 			l.backup()
@@ -184,17 +330,112 @@ func (l *Lexer) lexNumber() string {
 	}
 }
 
+// lexOperator reads the longest registered operator at the current position.
+// An operator word must not be followed by further letters.
+func (l *Lexer) lexOperator() (string, bool) {
+	if l.operators == nil {
+		return "", false
+	}
+
+	rest := l.input[l.pos:]
+	for _, symbol := range l.operators.symbols {
+		if len(rest) < len(symbol) || !strings.EqualFold(rest[:len(symbol)], symbol) {
+			continue
+		}
+
+		if isWordOperator(symbol) {
+			next, _ := utf8.DecodeRuneInString(rest[len(symbol):])
+			if unicode.IsLetter(next) || next == ':' || next == '.' {
+				continue
+			}
+		}
+
+		l.pos += len(symbol)
+		return rest[:len(symbol)], true
+	}
+
+	return "", false
+}
+
+// lexString reads a quoted text up to the closing quote. A backslash escapes
+// the next character, \n and \t are a new line and a tab.
+func (l *Lexer) lexString(quote rune) (string, bool) {
+	var lit strings.Builder
+	for {
+		switch r := l.next(); {
+		case r == EOF:
+			return lit.String(), false
+		case r == quote:
+			return lit.String(), true
+		case r == '\\':
+			switch escaped := l.next(); escaped {
+			case EOF:
+				return lit.String(), false
+			case 'n':
+				lit.WriteRune('\n')
+			case 't':
+				lit.WriteRune('\t')
+			default:
+				lit.WriteRune(escaped)
+			}
+		default:
+			lit.WriteRune(r)
+		}
+	}
+}
+
+// lexIdent reads a key or a function name. A key can have a namespace before
+// a colon and can be a path with a dot followed by a letter and an index like
+// [0]. A path can end with the wildcard .*.
 func (l *Lexer) lexIdent() string {
 	var lit string
 	for {
 		switch r := l.next(); {
 		case r == EOF:
 			return lit
-		case unicode.IsLetter(r) || r == ':':
+		case unicode.IsLetter(r):
+			lit = lit + string(r)
+		case (r == ':' || r == '.') && unicode.IsLetter(l.peek()):
 			lit = lit + string(r)
+		case r == '.' && l.peek() == '*':
+			l.next()
+			return lit + ".*"
+		case r == '[':
+			index, ok := l.lexIndex()
+			if !ok {
+				l.backup()
+				return lit
+			}
+			lit = lit + index
 		default:
 			l.backup()
 			return lit
 		}
 	}
 }
+
+// lexParam reads the name of a named placeholder after the colon, it consists
+// of letters, digits and underscores.
+func (l *Lexer) lexParam() string {
+	rest := l.input[l.pos:]
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' })
+	if end < 0 {
+		end = len(rest)
+	}
+
+	l.pos += end
+	return rest[:end]
+}
+
+// lexIndex reads the digits and the closing bracket of an index after the
+// opening bracket and returns the index with both brackets.
+func (l *Lexer) lexIndex() (string, bool) {
+	rest := l.input[l.pos:]
+	digits := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsDigit(r) })
+	if digits <= 0 || rest[digits] != ']' {
+		return "", false
+	}
+
+	l.pos += digits + 1
+	return "[" + rest[:digits+1], true
+}
@@ -21,6 +21,23 @@ func TestNewLexer(t *testing.T) {
 
 	l := NewLexer("abc")
 	assert.NotNil(t, l)
+	assert.Implements(t, (*QueryLexer)(nil), l)
+}
+
+func TestLexerSpan(t *testing.T) {
+	t.Parallel()
+
+	lexer := NewLexer("ab >= (1.5 ")
+	spans := []Span{}
+	for {
+		_, tok, _ := lexer.Lex()
+		spans = append(spans, lexer.span())
+		if tok == EOF {
+			break
+		}
+	}
+
+	assert.Equal(t, []Span{{0, 2}, {3, 5}, {6, 7}, {7, 10}, {11, 11}}, spans)
 }
 
 func TestLexer(t *testing.T) {
@@ -66,6 +83,26 @@ func TestLexer(t *testing.T) {
 			tokens: []Token{IDENT, IDENT, IDENT, EOF},
 			texts:  []string{"variableName", "O", "test", ""},
 		},
+		{
+			query:  "Andrew and order OR true False null",
+			tokens: []Token{IDENT, AND, IDENT, OR, TRUE, FALSE, NULL, EOF},
+			texts:  []string{"Andrew", "AND", "order", "OR", "TRUE", "FALSE", "NULL", ""},
+		},
+		{
+			query:  "a IS NOT EMPTY b is missing c Is Blank",
+			tokens: []Token{IDENT, IS, NOT, EMPTY, IDENT, IS, MISSING, IDENT, IS, BLANK, EOF},
+			texts:  []string{"a", "IS", "NOT", "EMPTY", "b", "IS", "MISSING", "c", "IS", "BLANK", ""},
+		},
+		{
+			query:  "a+b-c * -2/d%e",
+			tokens: []Token{IDENT, PLUS, IDENT, MINUS, IDENT, MUL, MINUS, NUMBER, DIV, IDENT, MOD, IDENT, EOF},
+			texts:  []string{"a", "+", "b", "-", "c", "*", "-", "2", "/", "d", "%", "e", ""},
+		},
+		{
+			query:  `len(a, "b\"c") = 'd\n' "e`,
+			tokens: []Token{IDENT, BRACKET_LEFT, IDENT, COMMA, STRING, BRACKET_RIGHT, EQ, STRING, ILLEGAL, EOF},
+			texts:  []string{"len", "(", "a", ",", "b\"c", ")", "=", "d\n", `"e`, ""},
+		},
 		{
 			query:  "variableName=b",
 			tokens: []Token{IDENT, EQ, IDENT, EOF},
@@ -106,6 +143,16 @@ func TestLexer(t *testing.T) {
 			tokens: []Token{IDENT, LT, NUMBER, EOF},
 			texts:  []string{"variableName", "<", "12,34", ""},
 		},
+		{
+			query:  "max(12,34, 5)",
+			tokens: []Token{IDENT, BRACKET_LEFT, NUMBER, COMMA, NUMBER, COMMA, NUMBER, BRACKET_RIGHT, EOF},
+			texts:  []string{"max", "(", "12", ",", "34", ",", "5", ")", ""},
+		},
+		{
+			query:  "max(a,(1,5))=1,5",
+			tokens: []Token{IDENT, BRACKET_LEFT, IDENT, COMMA, BRACKET_LEFT, NUMBER, BRACKET_RIGHT, BRACKET_RIGHT, EQ, NUMBER, EOF},
+			texts:  []string{"max", "(", "a", ",", "(", "1,5", ")", ")", "=", "1,5", ""},
+		},
 		{
 			query:  "a=b g>123",
 			tokens: []Token{IDENT, EQ, IDENT, IDENT, GT, NUMBER, EOF},
@@ -118,8 +165,38 @@ func TestLexer(t *testing.T) {
 		},
 		{
 			query:  "vari.able,Na(m)e<.1234",
-			tokens: []Token{IDENT, ILLEGAL, IDENT, ILLEGAL, IDENT, BRACKET_LEFT, IDENT, BRACKET_RIGHT, IDENT, LT, ILLEGAL, NUMBER, EOF},
-			texts:  []string{"vari", ".", "able", ",", "Na", "(", "m", ")", "e", "<", ".", "1234", ""},
+			tokens: []Token{IDENT, COMMA, IDENT, BRACKET_LEFT, IDENT, BRACKET_RIGHT, IDENT, LT, ILLEGAL, NUMBER, EOF},
+			texts:  []string{"vari.able", ",", "Na", "(", "m", ")", "e", "<", ".", "1234", ""},
+		},
+		{
+			query:  `items[0].sku="X1" AND customer.address.country=DE`,
+			tokens: []Token{IDENT, EQ, STRING, AND, IDENT, EQ, IDENT, EOF},
+			texts:  []string{"items[0].sku", "=", "X1", "AND", "customer.address.country", "=", "DE", ""},
+		},
+		{
+			query:  "ANY items: (sku=x) all q:approvals: none",
+			tokens: []Token{ANY, IDENT, COLON, BRACKET_LEFT, IDENT, EQ, IDENT, BRACKET_RIGHT, ALL, IDENT, COLON, NONE, EOF},
+			texts:  []string{"ANY", "items", ":", "(", "sku", "=", "x", ")", "ALL", "q:approvals", ":", "NONE", ""},
+		},
+		{
+			query:  "approval.*=approved EXISTS items[0].* a.*b",
+			tokens: []Token{IDENT, EQ, IDENT, EXISTS, IDENT, IDENT, IDENT, EOF},
+			texts:  []string{"approval.*", "=", "approved", "EXISTS", "items[0].*", "a.*", "b", ""},
+		},
+		{
+			query:  "vari. items[x] list[1][2]",
+			tokens: []Token{IDENT, ILLEGAL, IDENT, ILLEGAL, IDENT, ILLEGAL, IDENT, EOF},
+			texts:  []string{"vari", ".", "items", "[", "x", "]", "list[1][2]", ""},
+		},
+		{
+			query:  "a>b<c!",
+			tokens: []Token{IDENT, GT, IDENT, LT, IDENT, N, EOF},
+			texts:  []string{"a", ">", "b", "<", "c", "!", ""},
+		},
+		{
+			query:  "!!",
+			tokens: []Token{N, N, EOF},
+			texts:  []string{"!", "!", ""},
 		},
 	}
 
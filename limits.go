@@ -0,0 +1,117 @@
+package simplequery
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrQueryTooLong is returned if a query has more bytes than Limits.MaxBytes.
+	ErrQueryTooLong = errors.New("query is too long")
+	// ErrTooDeep is returned if a query is nested deeper than Limits.MaxDepth.
+	ErrTooDeep = errors.New("query is nested too deep")
+	// ErrTooManyNodes is returned if a query has more nodes than Limits.MaxNodes.
+	ErrTooManyNodes = errors.New("query has too many nodes")
+	// ErrListTooLong is returned as *EvalError if a list in the data has more
+	// items than Limits.MaxListLength.
+	ErrListTooLong = errors.New("list is too long")
+	// ErrTooManySteps is returned as *EvalError if the evaluation needs more
+	// steps than Limits.MaxSteps.
+	ErrTooManySteps = errors.New("evaluation has too many steps")
+)
+
+// DefaultMaxDepth is the nesting limit of a query without MaxDepth, so a deeply
+// nested query fails with ErrTooDeep instead of exhausting the stack.
+const DefaultMaxDepth = 1000
+
+// Limits restricts the resources a query may use, e.g. for queries of tenants.
+// A zero limit is unlimited, except for MaxDepth.
+type Limits struct {
+	// MaxBytes is the maximum length of the query.
+	MaxBytes int
+	// MaxDepth is the maximum nesting of brackets, negations, quantifiers,
+	// function calls and unary minus. Zero is DefaultMaxDepth.
+	MaxDepth int
+	// MaxNodes is the maximum number of keys, literals, operators and calls.
+	MaxNodes int
+	// MaxListLength is the maximum number of items of a list in the data.
+	MaxListLength int
+	// MaxSteps is the maximum number of nodes evaluated by a match, items of
+	// quantifiers and keys matching a wildcard key are evaluated repeatedly.
+	MaxSteps int
+}
+
+// enter increases the nesting depth of the parser, leave must be called when
+// the nested part is read.
+func (p *parser) enter() error {
+	p.depth++
+	limit := p.limits.MaxDepth
+	if limit <= 0 {
+		limit = DefaultMaxDepth
+	}
+	if p.depth > limit {
+		return fmt.Errorf("%w on %d: the limit is %d", ErrTooDeep, p.cur.pos, limit)
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+// checkNodes ensures that the tree has not more nodes than the limit.
+func (l Limits) checkNodes(root node) error {
+	if l.MaxNodes > 0 {
+		if nodes := countNodes(root); nodes > l.MaxNodes {
+			return fmt.Errorf("%w: %d nodes, the limit is %d", ErrTooManyNodes, nodes, l.MaxNodes)
+		}
+	}
+	return nil
+}
+
+// checkBytes ensures that the query is not longer than the limit.
+func (l Limits) checkBytes(input string) error {
+	if l.MaxBytes > 0 && len(input) > l.MaxBytes {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrQueryTooLong, len(input), l.MaxBytes)
+	}
+	return nil
+}
+
+// checkList ensures that a list value is not longer than the limit.
+func (l Limits) checkList(value Value) error {
+	if l.MaxListLength > 0 && value.Kind() == KindList && len(value.List()) > l.MaxListLength {
+		return fmt.Errorf("%w: %d items, the limit is %d", ErrListTooLong, len(value.List()), l.MaxListLength)
+	}
+	return nil
+}
+
+// countNodes returns the number of nodes of the tree.
+func countNodes(n node) int {
+	switch n := n.(type) {
+	case *binaryNode:
+		return 1 + countNodes(n.left) + countNodes(n.right)
+	case *notNode:
+		return 1 + countNodes(n.x)
+	case *negNode:
+		return 1 + countNodes(n.x)
+	case *groupNode:
+		return 1 + countNodes(n.x)
+	case *isNode:
+		return 2
+	case *quantifierNode:
+		if n.wildcard {
+			return 1 + countNodes(n.x)
+		}
+		return 2 + countNodes(n.x)
+	case *callNode:
+		count := 1
+		for _, arg := range n.args {
+			count += countNodes(arg)
+		}
+		return count
+	case *macroNode:
+		return 1 + countNodes(n.macro.root)
+	}
+
+	return 1
+}
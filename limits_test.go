@@ -0,0 +1,101 @@
+package simplequery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsCompile(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		query  string
+		limits Limits
+		err    error
+	}{
+		{query: "amount > 1", limits: Limits{MaxBytes: 10}},
+		{query: "amount > 10", limits: Limits{MaxBytes: 10}, err: ErrQueryTooLong},
+		{query: "((a))", limits: Limits{MaxDepth: 2}},
+		{query: "(((a)))", limits: Limits{MaxDepth: 2}, err: ErrTooDeep},
+		{query: "!!!a", limits: Limits{MaxDepth: 2}, err: ErrTooDeep},
+		{query: "a > ---1", limits: Limits{MaxDepth: 2}, err: ErrTooDeep},
+		{query: "lower(lower(lower(a))) = x", limits: Limits{MaxDepth: 2}, err: ErrTooDeep},
+		{query: "ANY items: ALL tags: !x", limits: Limits{MaxDepth: 2}, err: ErrTooDeep},
+		{query: "(a) AND (b) AND !c", limits: Limits{MaxDepth: 1}},
+		{query: strings.Repeat("(", 100000) + "a" + strings.Repeat(")", 100000), limits: Limits{MaxDepth: 100}, err: ErrTooDeep},
+		{query: "a AND b > 1", limits: Limits{MaxNodes: 5}},
+		{query: "a AND b > 1 OR c", limits: Limits{MaxNodes: 5}, err: ErrTooManyNodes},
+		{query: "lower(a) = x", limits: Limits{MaxNodes: 3}, err: ErrTooManyNodes},
+		{query: "a IS EMPTY", limits: Limits{MaxNodes: 1}, err: ErrTooManyNodes},
+	}
+
+	for _, testCase := range testCases {
+		_, err := Compile(testCase.query, WithLimits(testCase.limits))
+		if testCase.err == nil {
+			assert.NoError(t, err, testCase.query)
+		} else {
+			assert.ErrorIs(t, err, testCase.err, testCase.query)
+		}
+	}
+
+	// without limits the depth is limited to DefaultMaxDepth
+	for _, query := range []string{"!", "a AND !", "!!"} {
+		_, err := Compile(query)
+		assert.Error(t, err, query)
+	}
+	_, err := Compile(strings.Repeat("!", DefaultMaxDepth) + "a")
+	assert.NoError(t, err)
+	for _, query := range []string{
+		strings.Repeat("!", 100000) + "a",
+		strings.Repeat("(", 100000) + "a" + strings.Repeat(")", 100000),
+		"a > " + strings.Repeat("-", 100000) + "1",
+	} {
+		_, err := Compile(query)
+		assert.ErrorIs(t, err, ErrTooDeep)
+	}
+}
+
+func TestLimitsMatch(t *testing.T) {
+	t.Parallel()
+
+	data := AnyMap{"items": []any{1, 2, 3}, "a": "1", "prices": map[string]any{"eu": 1, "us": 2}}
+
+	testCases := []struct {
+		query  string
+		limits Limits
+		text   string
+		err    error
+	}{
+		{query: "ANY items: true", limits: Limits{MaxListLength: 3}},
+		{query: "ANY items: true", limits: Limits{MaxListLength: 2}, text: "items", err: ErrListTooLong},
+		{query: "a AND items = 1", limits: Limits{MaxListLength: 2}, text: "items", err: ErrListTooLong},
+		{query: "a AND a AND a", limits: Limits{MaxSteps: 5}},
+		{query: "a AND a AND a AND a", limits: Limits{MaxSteps: 5}, text: "a", err: ErrTooManySteps},
+		{query: "ANY items: true", limits: Limits{MaxSteps: 4}},
+		{query: "ALL items: true", limits: Limits{MaxSteps: 3}, text: "true", err: ErrTooManySteps},
+		{query: "prices.* > 1", limits: Limits{MaxSteps: 5}, text: "prices.*", err: ErrTooManySteps},
+	}
+
+	for _, testCase := range testCases {
+		q, err := Compile(testCase.query)
+		assert.NoError(t, err, testCase.query)
+
+		ok, explanation, err := q.MatchSource(data, WithLimits(testCase.limits))
+		if testCase.err == nil {
+			assert.NoError(t, err, testCase.query)
+			assert.True(t, ok, testCase.query)
+			continue
+		}
+
+		assert.False(t, ok, testCase.query)
+		assert.ErrorIs(t, err, testCase.err, testCase.query)
+		var evalErr *EvalError
+		if assert.True(t, errors.As(err, &evalErr), testCase.query) {
+			assert.Equal(t, testCase.text, evalErr.Text, testCase.query)
+		}
+		assert.NotNil(t, explanation, testCase.query)
+	}
+}
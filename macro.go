@@ -0,0 +1,179 @@
+package simplequery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Library is a set of named sub-queries, called macros, which queries
+// reference with @name, e.g. @isEU AND amount>100. It is passed with
+// WithLibrary to Compile.
+type Library struct {
+	sources map[string]string
+	macros  map[string]*macro
+	options *options
+	// stack holds the macros which are compiled to detect cycles
+	stack []string
+}
+
+// macro is a compiled macro. Its spans point into its own source.
+type macro struct {
+	name   string
+	source string
+	root   node
+	// condition reports whether the macro can be used as condition.
+	condition bool
+}
+
+// MacroError is returned by NewLibrary for a macro which can not be compiled.
+// Positions in Err point into the source of the macro.
+type MacroError struct {
+	Name string
+	Err  error
+}
+
+func (e *MacroError) Error() string {
+	return fmt.Sprintf("macro @%s: %s", e.Name, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *MacroError) Unwrap() error {
+	return e.Err
+}
+
+// NewLibrary compiles the macros by name. A macro is a condition or a value,
+// it can reference other macros in any order but not itself. Names consist of
+// letters, digits and underscores and start with a letter. The options are
+// used to compile each macro, placeholders are not allowed in macros.
+func NewLibrary(macros map[string]string, opts ...Option) (*Library, error) {
+	l := &Library{sources: macros, macros: map[string]*macro{}, options: newOptions(opts)}
+
+	names := make([]string, 0, len(macros))
+	for name := range macros {
+		if !isMacroName(name) {
+			return nil, &MacroError{Name: name, Err: errors.New("invalid name")}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := l.lookup(name); err != nil {
+			return nil, err
+		}
+	}
+
+	l.stack = nil
+	return l, nil
+}
+
+// WithLibrary makes the macros of the library available to the query.
+// It is used when the query is compiled.
+func WithLibrary(library *Library) Option {
+	return func(o *options) {
+		o.library = library
+	}
+}
+
+// Names returns the names of the macros in alphabetical order.
+func (l *Library) Names() []string {
+	names := make([]string, 0, len(l.macros))
+	for name := range l.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup returns the compiled macro, it is compiled on the first lookup.
+func (l *Library) lookup(name string) (*macro, error) {
+	if m, ok := l.macros[name]; ok {
+		return m, nil
+	}
+
+	source, ok := l.sources[name]
+	if !ok {
+		return nil, nil
+	}
+
+	for i, compiling := range l.stack {
+		if compiling == name {
+			cycle := append(l.stack[i:], name)
+			return nil, &MacroError{Name: name, Err: fmt.Errorf("cycle @%s", strings.Join(cycle, " -> @"))}
+		}
+	}
+
+	l.stack = append(l.stack, name)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	o := *l.options
+	o.library = l
+	root, p, err := parseExpression(NewLexer(source), &o)
+	if err == nil && !isValue(root) {
+		err = p.checkCondition(root)
+	}
+	if err == nil {
+		walkParams(root, func(n *paramNode) {
+			if err == nil {
+				err = p.illegalNode(n, "is a placeholder")
+			}
+		})
+	}
+	if err != nil {
+		if _, ok := err.(*MacroError); ok {
+			return nil, err
+		}
+		return nil, &MacroError{Name: name, Err: err}
+	}
+
+	m := &macro{name: name, source: source, root: root, condition: p.checkCondition(root) == nil}
+	l.macros[name] = m
+	return m, nil
+}
+
+// parseMacro reads a reference to a macro of the library.
+func (p *parser) parseMacro(ref item) (node, error) {
+	if p.library == nil {
+		return nil, p.illegal()
+	}
+
+	m, err := p.library.lookup(ref.text)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, fmt.Errorf("illegal query party on %d: @%s is an unknown macro", ref.span.Start+1, ref.text)
+	}
+
+	p.next()
+	return &macroNode{Span: ref.span, macro: m}, nil
+}
+
+// lexMacro reads the name of a macro after the @.
+func (l *Lexer) lexMacro() (string, bool) {
+	rest := l.input[l.pos:]
+	if !strings.HasPrefix(rest, "@") {
+		return "", false
+	}
+
+	end := strings.IndexFunc(rest[1:], func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' })
+	if end < 0 {
+		end = len(rest) - 1
+	}
+	if !isMacroName(rest[1 : end+1]) {
+		return "", false
+	}
+
+	l.pos += end + 1
+	return rest[1 : end+1], true
+}
+
+func isMacroName(name string) bool {
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		return false
+	}
+	return strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }) < 0
+}
@@ -0,0 +1,139 @@
+package simplequery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLibrary(t *testing.T) *Library {
+	library, err := NewLibrary(map[string]string{
+		"isEU":     "country = DE OR country = FR OR country = IT",
+		"bigEU":    "@isEU AND @gross > 1000",
+		"gross":    "amount * 1.19",
+		"ratio":    "amount / zero > 1",
+		"hasItems": "ANY items: sku",
+	})
+	assert.NoError(t, err)
+
+	return library
+}
+
+func TestLibrary(t *testing.T) {
+	t.Parallel()
+
+	library := testLibrary(t)
+	assert.Equal(t, []string{"bigEU", "gross", "hasItems", "isEU", "ratio"}, library.Names())
+
+	data := map[string]string{"country": "DE", "amount": "900", "zero": "0"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "@isEU AND amount>100", ok: true},
+		{query: "@isEU amount>1000", ok: false},
+		{query: "!@isEU", ok: false},
+		{query: "@bigEU", ok: true},
+		{query: "@gross > 1000 AND @gross < 1100", ok: true},
+		{query: "round(@gross) = 1071", ok: true},
+		{query: "(@isEU)", ok: true},
+		{query: "@hasItems OR @isEU", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data, WithLibrary(library))
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	for _, query := range []string{"@gross", "@unknown", "@isEU > 1", "@", "@1"} {
+		_, err := Compile(query, WithLibrary(library))
+		assert.Error(t, err, query)
+	}
+
+	// without a library @ is no macro
+	_, err := Compile("@isEU")
+	assert.Error(t, err)
+}
+
+func TestLibraryErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		macros map[string]string
+		name   string
+		err    string
+	}{
+		{macros: map[string]string{"a": "@b AND x", "b": "y OR @a"}, name: "a", err: "macro @a: cycle @a -> @b -> @a"},
+		{macros: map[string]string{"self": "x AND @self"}, name: "self", err: "macro @self: cycle @self -> @self"},
+		{macros: map[string]string{"a": "x AND @b", "b": "(y"}, name: "b", err: "macro @b: illegal query party EOF on 2: "},
+		{macros: map[string]string{"a": "x AND @b"}, name: "a", err: "macro @a: illegal query party on 7: @b is an unknown macro"},
+		{macros: map[string]string{"a": "amount > :threshold"}, name: "a", err: "macro @a: illegal query party on 10: :threshold is a placeholder"},
+		{macros: map[string]string{"a": "x AND 5"}, name: "a", err: "macro @a: illegal query party on 7: 5 is no condition"},
+		{macros: map[string]string{"1a": "x"}, name: "1a", err: "macro @1a: invalid name"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := NewLibrary(testCase.macros)
+		assert.EqualError(t, err, testCase.err)
+
+		var macroErr *MacroError
+		if assert.True(t, errors.As(err, &macroErr)) {
+			assert.Equal(t, testCase.name, macroErr.Name)
+		}
+	}
+}
+
+func TestMacroExplanation(t *testing.T) {
+	t.Parallel()
+
+	library := testLibrary(t)
+	data := map[string]string{"country": "DE", "amount": "900", "zero": "0"}
+
+	ok, explanation, err := Match("@isEU AND @gross > 1000", data, WithLibrary(library))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []bool{true, false, false, true}, explanation.Details())
+
+	isEU := explanation.Children[0]
+	assert.Equal(t, NodeMacro, isEU.Type)
+	assert.Equal(t, "isEU", isEU.Key)
+	assert.Equal(t, "@isEU", isEU.Text)
+	assert.Equal(t, "country = DE OR country = FR OR country = IT", isEU.Children[0].Text)
+
+	assert.Equal(t, "@isEU AND @gross > 1000 was true\n"+
+		"  @isEU was true\n"+
+		"    country = DE OR country = FR OR country = IT was true\n"+
+		"      country = DE was true because country=DE\n"+
+		"      country = FR was not evaluated\n"+
+		"      country = IT was not evaluated\n"+
+		"  @gross > 1000 was true because amount=900\n", explanation.String())
+
+	// errors point into the source of the macro
+	_, _, err = Match("country AND @ratio", data, WithLibrary(library))
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "ratio", evalErr.Macro)
+		assert.Equal(t, "amount / zero", evalErr.Text)
+		assert.Equal(t, Span{0, 13}, evalErr.Span)
+		assert.EqualError(t, err, "evaluation of amount / zero on 1 of macro @ratio failed: division by zero")
+	}
+
+	// also inside the items of a quantifier
+	itemLibrary, err := NewLibrary(map[string]string{"m": "ANY items: x / y > 1"})
+	assert.NoError(t, err)
+	_, _, err = MatchJSON("@m", []byte(`{"items": [{"x": 1, "y": 0}]}`), WithLibrary(itemLibrary))
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "m", evalErr.Macro)
+		assert.Equal(t, "x / y", evalErr.Text)
+		assert.EqualError(t, err, "evaluation of x / y on 12 of macro @m failed: division by zero")
+	}
+
+	q, err := Compile("@bigEU", WithLibrary(library))
+	assert.NoError(t, err)
+	ok, _, err = q.MatchJSON([]byte(`{"country": "FR", "amount": 1000}`))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,414 @@
+package simplequery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the JSON representation of a query.
+const SchemaVersion = 1
+
+// jsonQuery is the JSON representation of a query.
+type jsonQuery struct {
+	Version int       `json:"version"`
+	Query   *jsonNode `json:"query"`
+}
+
+// jsonNode is a node of the JSON representation. The fields depend on the type:
+//
+//	and, or     conditions
+//	not         condition
+//	compare     operator, left, right
+//	calc        operator, left, right
+//	neg         operand
+//	predicate   key, predicate (EMPTY, MISSING, BLANK, NULL or EXISTS), negate
+//	quantifier  quantifier (ANY, ALL or NONE), key, condition; without key the
+//	            condition has a wildcard key
+//	key         name
+//	literal     value, text of a number which is written as no JSON number,
+//	            e.g. 01234
+//	call        name, arguments
+//	param       name, a positional placeholder has no name
+//	macro       name
+type jsonNode struct {
+	Type       string          `json:"type"`
+	Operator   string          `json:"operator,omitempty"`
+	Quantifier string          `json:"quantifier,omitempty"`
+	Predicate  string          `json:"predicate,omitempty"`
+	Negate     bool            `json:"negate,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Key        string          `json:"key,omitempty"`
+	Value      json.RawMessage `json:"value,omitempty"`
+	Text       string          `json:"text,omitempty"`
+	Left       *jsonNode       `json:"left,omitempty"`
+	Right      *jsonNode       `json:"right,omitempty"`
+	Operand    *jsonNode       `json:"operand,omitempty"`
+	Condition  *jsonNode       `json:"condition,omitempty"`
+	Conditions []*jsonNode     `json:"conditions,omitempty"`
+	Arguments  []*jsonNode     `json:"arguments,omitempty"`
+}
+
+// MarshalJSON encodes the query tree with the SchemaVersion. Chains of AND and
+// OR are lists of conditions, brackets are not encoded.
+func (q *Query) MarshalJSON() ([]byte, error) {
+	root, err := marshalNode(q.root)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonQuery{Version: SchemaVersion, Query: root})
+}
+
+// UnmarshalJSON decodes a query encoded by MarshalJSON with the default
+// options. Use UnmarshalQuery for custom functions, operators or macros.
+func (q *Query) UnmarshalJSON(data []byte) error {
+	decoded, err := UnmarshalQuery(data)
+	if err != nil {
+		return err
+	}
+
+	*q = *decoded
+	return nil
+}
+
+// UnmarshalQuery decodes a query encoded by MarshalJSON and compiles it with
+// the options. The tree is validated like a parsed query, e.g. the functions
+// must exist and comparisons must have values.
+func UnmarshalQuery(data []byte, opts ...Option) (*Query, error) {
+	var decoded jsonQuery
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+
+	if decoded.Version != SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d", decoded.Version)
+	}
+	if decoded.Query == nil {
+		return nil, errors.New("query is missing")
+	}
+
+	u := &unmarshaler{options: newOptions(opts)}
+	root, err := u.node(decoded.Query, "query")
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	format(&b, root)
+	return Compile(b.String(), opts...)
+}
+
+func marshalNode(n node) (*jsonNode, error) {
+	switch n := n.(type) {
+	case *binaryNode:
+		if n.op == AND || n.op == OR {
+			j := &jsonNode{Type: strings.ToLower(n.op.String())}
+			for _, condition := range chain(n, n.op, nil) {
+				child, err := marshalNode(condition)
+				if err != nil {
+					return nil, err
+				}
+				j.Conditions = append(j.Conditions, child)
+			}
+			return j, nil
+		}
+
+		left, err := marshalNode(n.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := marshalNode(n.right)
+		if err != nil {
+			return nil, err
+		}
+
+		j := &jsonNode{Type: "compare", Operator: n.op.String(), Left: left, Right: right}
+		if isArithmetic(n.op) {
+			j.Type = "calc"
+		}
+		if n.operator != nil {
+			j.Operator = n.symbol
+		}
+		return j, nil
+	case *notNode:
+		x, err := marshalNode(n.x)
+		return &jsonNode{Type: "not", Condition: x}, err
+	case *negNode:
+		x, err := marshalNode(n.x)
+		return &jsonNode{Type: "neg", Operand: x}, err
+	case *groupNode:
+		return marshalNode(n.x)
+	case *quantifierNode:
+		x, err := marshalNode(n.x)
+		j := &jsonNode{Type: "quantifier", Quantifier: n.quantifier.String(), Condition: x}
+		if !n.wildcard {
+			j.Key = n.key.name
+		}
+		return j, err
+	case *isNode:
+		return &jsonNode{Type: "predicate", Key: n.key.name, Predicate: n.predicate.String(), Negate: n.negate}, nil
+	case *keyNode:
+		return &jsonNode{Type: "key", Name: n.name}, nil
+	case *literalNode:
+		j := &jsonNode{Type: "literal"}
+		value := n.value
+		// a number which is no JSON number, e.g. 01234, keeps its text
+		if value.Kind() == KindNumber && !json.Valid([]byte(value.String())) {
+			number, _ := value.Number()
+			j.Text, value = value.String(), NumberValue(number)
+		}
+		var err error
+		j.Value, err = marshalValue(value)
+		return j, err
+	case *callNode:
+		j := &jsonNode{Type: "call", Name: n.name, Arguments: []*jsonNode{}}
+		for _, arg := range n.args {
+			x, err := marshalNode(arg)
+			if err != nil {
+				return nil, err
+			}
+			j.Arguments = append(j.Arguments, x)
+		}
+		return j, nil
+	case *paramNode:
+		return &jsonNode{Type: "param", Name: n.name}, nil
+	case *macroNode:
+		return &jsonNode{Type: "macro", Name: n.macro.name}, nil
+	}
+
+	return nil, fmt.Errorf("unknown node %T", n)
+}
+
+// chain returns the operands of a chain of the same logical operator.
+func chain(n node, op Token, operands []node) []node {
+	if b, ok := n.(*binaryNode); ok && b.op == op {
+		operands = chain(b.left, op, operands)
+		return chain(b.right, op, operands)
+	}
+	return append(operands, n)
+}
+
+// marshalValue encodes a literal, a number as written and a time as text.
+func marshalValue(v Value) (json.RawMessage, error) {
+	switch v.Kind() {
+	case KindNull:
+		return json.RawMessage("null"), nil
+	case KindBool:
+		return json.Marshal(v.Truthy())
+	case KindNumber:
+		return json.Marshal(json.Number(v.String()))
+	case KindTime:
+		t, _ := v.Time()
+		return json.Marshal(t.Format(time.RFC3339Nano))
+	case KindString:
+		return json.Marshal(v.String())
+	default:
+		return nil, fmt.Errorf("literal %s can not be encoded", v.String())
+	}
+}
+
+// unmarshaler builds a query tree from the JSON representation. The tree is
+// formatted and compiled, which checks the operands, functions and macros.
+type unmarshaler struct {
+	options *options
+}
+
+func (u *unmarshaler) node(j *jsonNode, path string) (node, error) {
+	if j == nil {
+		return nil, fmt.Errorf("%s is missing", path)
+	}
+
+	switch j.Type {
+	case "and", "or":
+		if len(j.Conditions) < 2 {
+			return nil, fmt.Errorf("%s needs at least two conditions", path)
+		}
+
+		op := Token(AND)
+		if j.Type == "or" {
+			op = OR
+		}
+
+		var n node
+		for i, condition := range j.Conditions {
+			x, err := u.node(condition, fmt.Sprintf("%s.conditions[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			if n == nil {
+				n = x
+				continue
+			}
+			n = &binaryNode{op: op, left: n, right: x}
+		}
+		return n, nil
+	case "not":
+		x, err := u.node(j.Condition, path+".condition")
+		return &notNode{x: x}, err
+	case "neg":
+		x, err := u.node(j.Operand, path+".operand")
+		return &negNode{x: x}, err
+	case "compare", "calc":
+		left, err := u.node(j.Left, path+".left")
+		if err != nil {
+			return nil, err
+		}
+		right, err := u.node(j.Right, path+".right")
+		if err != nil {
+			return nil, err
+		}
+
+		n := &binaryNode{left: left, right: right}
+		tok, ok := operatorTokens[j.Operator]
+		switch {
+		case ok && isArithmetic(tok) == (j.Type == "calc"):
+			n.op = tok
+		case j.Type == "compare" && u.options.operators != nil:
+			if n.operator, ok = u.options.operators.lookup(j.Operator); !ok {
+				return nil, fmt.Errorf("%s has the unknown operator %q", path, j.Operator)
+			}
+			n.op, n.symbol = OPERATOR, j.Operator
+		default:
+			return nil, fmt.Errorf("%s has the unknown operator %q", path, j.Operator)
+		}
+		return n, nil
+	case "predicate":
+		key, err := u.key(j.Key, path)
+		if err != nil {
+			return nil, err
+		}
+
+		predicate, ok := keywords[strings.ToUpper(j.Predicate)]
+		if !ok || predicate != EMPTY && predicate != MISSING && predicate != BLANK && predicate != NULL && predicate != EXISTS {
+			return nil, fmt.Errorf("%s has the unknown predicate %q", path, j.Predicate)
+		}
+		if predicate == EXISTS && j.Negate {
+			return nil, fmt.Errorf("%s can not negate EXISTS", path)
+		}
+		return &isNode{key: key, predicate: predicate, negate: j.Negate}, nil
+	case "quantifier":
+		quantifier, ok := keywords[strings.ToUpper(j.Quantifier)]
+		if !ok || quantifier != ANY && quantifier != ALL && quantifier != NONE {
+			return nil, fmt.Errorf("%s has the unknown quantifier %q", path, j.Quantifier)
+		}
+
+		x, err := u.node(j.Condition, path+".condition")
+		if err != nil {
+			return nil, err
+		}
+		if j.Key == "" {
+			keys := wildcardKeys(x)
+			if len(keys) == 0 {
+				return nil, fmt.Errorf("%s has no key and no wildcard key", path)
+			}
+			return &quantifierNode{quantifier: quantifier, key: keys[0], x: x, wildcard: true}, nil
+		}
+
+		key, err := u.key(j.Key, path)
+		return &quantifierNode{quantifier: quantifier, key: key, x: x}, err
+	case "key":
+		return u.key(j.Name, path)
+	case "literal":
+		if j.Value == nil {
+			return nil, fmt.Errorf("%s has no value", path)
+		}
+
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(j.Value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		switch value := value.(type) {
+		case json.Number:
+			return u.number(value, j.Text, path)
+		case nil, bool, string:
+			if j.Text != "" {
+				return nil, fmt.Errorf("%s has a text but no number value", path)
+			}
+			return &literalNode{value: ValueOf(value)}, nil
+		}
+		return nil, fmt.Errorf("%s has no text, number, bool or null value", path)
+	case "call":
+		if !isName(j.Name, IDENT) {
+			return nil, fmt.Errorf("%s has the invalid function name %q", path, j.Name)
+		}
+
+		n := &callNode{name: j.Name}
+		for i, arg := range j.Arguments {
+			x, err := u.node(arg, fmt.Sprintf("%s.arguments[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			n.args = append(n.args, x)
+		}
+		return n, nil
+	case "param":
+		if j.Name != "" && !isName(":"+j.Name, PARAM) {
+			return nil, fmt.Errorf("%s has the invalid name %q", path, j.Name)
+		}
+		return &paramNode{name: j.Name}, nil
+	case "macro":
+		if !isMacroName(j.Name) {
+			return nil, fmt.Errorf("%s has the invalid name %q", path, j.Name)
+		}
+		if u.options.library == nil || u.options.library.sources[j.Name] == "" {
+			return nil, fmt.Errorf("%s references the unknown macro @%s", path, j.Name)
+		}
+		return &macroNode{macro: &macro{name: j.Name}}, nil
+	}
+
+	return nil, fmt.Errorf("%s has the unknown type %q", path, j.Type)
+}
+
+// number returns the literal of a number. An exponent is written without, the
+// query has no exponents. The text keeps a number as written, e.g. 01234.
+func (u *unmarshaler) number(value json.Number, text string, path string) (*literalNode, error) {
+	number, err := value.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s has the invalid number %s", path, value)
+	}
+
+	if text == "" {
+		text = value.String()
+		if strings.ContainsAny(text, "eE") {
+			text = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		return &literalNode{value: numberLiteral(text)}, nil
+	}
+
+	if written, err := strconv.ParseFloat(text, 64); err != nil || written != number || !isName(text, NUMBER) {
+		return nil, fmt.Errorf("%s has the text %q which is not the number %s", path, text, value)
+	}
+	return &literalNode{value: numberLiteral(text)}, nil
+}
+
+// key returns the node of a key, which must be a single identifier.
+func (u *unmarshaler) key(name string, path string) (*keyNode, error) {
+	if !isName(name, IDENT) {
+		return nil, fmt.Errorf("%s has the invalid key %q", path, name)
+	}
+	return &keyNode{name: name}, nil
+}
+
+// isName reports whether the text is read as a single token of the type.
+// Keywords which are keys outside of their place are identifiers.
+func isName(text string, tok Token) bool {
+	lexer := NewLexer(text)
+	_, first, _ := lexer.Lex()
+	return (first == tok || tok == IDENT && isWord(first)) && lexer.pos == len(text)
+}
+
+// operatorTokens are the built-in comparison and calculation operators.
+var operatorTokens = map[string]Token{
+	tokens[EQ]: EQ, tokens[NE]: NE, tokens[GT]: GT, tokens[GTE]: GTE, tokens[LT]: LT, tokens[LTE]: LTE,
+	tokens[PLUS]: PLUS, tokens[MINUS]: MINUS, tokens[MUL]: MUL, tokens[DIV]: DIV, tokens[MOD]: MOD,
+}
@@ -0,0 +1,153 @@
+package simplequery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	q, err := Compile("(a OR b OR c) AND amount * 2 > 10 AND !EXISTS blocked")
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(q)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"version":1,"query":{"type":"and","conditions":[
+		{"type":"or","conditions":[{"type":"key","name":"a"},{"type":"key","name":"b"},{"type":"key","name":"c"}]},
+		{"type":"compare","operator":">",
+			"left":{"type":"calc","operator":"*","left":{"type":"key","name":"amount"},"right":{"type":"literal","value":2}},
+			"right":{"type":"literal","value":10}},
+		{"type":"not","condition":{"type":"predicate","key":"blocked","predicate":"EXISTS"}}
+	]}}`, string(data))
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	registry := testOperators(t)
+	library := testLibrary(t)
+
+	queries := []string{
+		"a and(b OR c )",
+		"!(a OR b) c",
+		"(a + b) * c > (d - e) - f",
+		"a - (b - c) > a / (b * c) OR -(a + 1) < - -b",
+		"(a > 1) = (b < 2)",
+		"region=(north) OR region=north AND name = 'say \"hi\"'",
+		"lower(name) = anna AND len(name) > 3",
+		"c IS NULL AND b IS NOT EMPTY OR EXISTS items AND d IS NOT MISSING",
+		"ANY items: sku = a AND ALL tags: !x AND NONE prices.* > 1",
+		"a = ? OR b > :x AND c = TRUE AND d = NULL AND e = 1.5",
+		"tags@>b and (city geo.within germany) @> true",
+		"@isEU and @gross>100",
+		"zip = 01234 AND a = 10.0 AND b = 1. AND c = 1000000000000000000000",
+	}
+
+	for _, query := range queries {
+		q, err := Compile(query, WithOperators(registry), WithLibrary(library))
+		if !assert.NoError(t, err, query) {
+			continue
+		}
+
+		data, err := json.Marshal(q)
+		if !assert.NoError(t, err, query) {
+			continue
+		}
+
+		decoded, err := UnmarshalQuery(data, WithOperators(registry), WithLibrary(library))
+		if !assert.NoError(t, err, string(data)) {
+			continue
+		}
+		assert.Equal(t, Format(q), Format(decoded), query)
+
+		again, err := json.Marshal(decoded)
+		assert.NoError(t, err, query)
+		assert.JSONEq(t, string(data), string(again), query)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	var q Query
+	err := json.Unmarshal([]byte(`{"version":1,"query":{"type":"compare","operator":">=",
+		"left":{"type":"key","name":"amount"},"right":{"type":"literal","value":100}}}`), &q)
+	assert.NoError(t, err)
+	assert.Equal(t, "amount >= 100", q.String())
+
+	ok, _, err := q.MatchAny(map[string]any{"amount": 150})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = q.MatchAny(map[string]any{"amount": 50})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// numbers keep their text, exponents are written out
+	err = json.Unmarshal([]byte(`{"version":1,"query":{"type":"and","conditions":[
+		{"type":"compare","operator":"=","left":{"type":"key","name":"zip"},"right":{"type":"literal","value":1234,"text":"01234"}},
+		{"type":"compare","operator":"=","left":{"type":"key","name":"a"},"right":{"type":"literal","value":10.0}},
+		{"type":"compare","operator":"<","left":{"type":"key","name":"b"},"right":{"type":"literal","value":1.5e3}}]}}`), &q)
+	assert.NoError(t, err)
+	assert.Equal(t, "zip = 01234 AND a = 10.0 AND b < 1500", q.String())
+
+	ok, _, err = q.Match(map[string]string{"zip": "01234", "a": "10.0", "b": "1"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = q.Match(map[string]string{"zip": "1234", "a": "10.0", "b": "1"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	data, err := json.Marshal(&q)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"value":1234,"text":"01234"`)
+	assert.Contains(t, string(data), `"value":10.0}`)
+}
+
+func TestUnmarshalQueryErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		json string
+		err  string
+	}{
+		{json: `{"query":{"type":"key","name":"a"}}`, err: "unsupported schema version 0"},
+		{json: `{"version":2,"query":{"type":"key","name":"a"}}`, err: "unsupported schema version 2"},
+		{json: `{"version":1}`, err: "query is missing"},
+		{json: `{"version":1,"query":{"type":"key","name":"a"},"extra":1}`, err: "json: unknown field \"extra\""},
+		{json: `{"version":1,"query":{"type":"xor"}}`, err: "query has the unknown type \"xor\""},
+		{json: `{"version":1,"query":{"type":"and","conditions":[{"type":"key","name":"a"}]}}`, err: "query needs at least two conditions"},
+		{json: `{"version":1,"query":{"type":"not"}}`, err: "query.condition is missing"},
+		{json: `{"version":1,"query":{"type":"key","name":"a b"}}`, err: "query has the invalid key \"a b\""},
+		{json: `{"version":1,"query":{"type":"key","name":"and"}}`, err: "query has the invalid key \"and\""},
+		{json: `{"version":1,"query":{"type":"compare","operator":"~","left":{"type":"key","name":"a"},"right":{"type":"literal","value":1}}}`, err: "query has the unknown operator \"~\""},
+		{json: `{"version":1,"query":{"type":"calc","operator":">","left":{"type":"key","name":"a"},"right":{"type":"literal","value":1}}}`, err: "query has the unknown operator \">\""},
+		{json: `{"version":1,"query":{"type":"predicate","key":"a","predicate":"TRUE"}}`, err: "query has the unknown predicate \"TRUE\""},
+		{json: `{"version":1,"query":{"type":"quantifier","quantifier":"SOME","key":"a","condition":{"type":"key","name":"b"}}}`, err: "query has the unknown quantifier \"SOME\""},
+		{json: `{"version":1,"query":{"type":"quantifier","quantifier":"ANY","condition":{"type":"key","name":"b"}}}`, err: "query has no key and no wildcard key"},
+		{json: `{"version":1,"query":{"type":"or","conditions":[{"type":"key","name":"a"},{"type":"literal","value":[1]}]}}`, err: "query.conditions[1] has no text, number, bool or null value"},
+		{json: `{"version":1,"query":{"type":"literal","value":1e400}}`, err: "query has the invalid number 1e400"},
+		{json: `{"version":1,"query":{"type":"literal","value":1234,"text":"1235"}}`, err: "query has the text \"1235\" which is not the number 1234"},
+		{json: `{"version":1,"query":{"type":"literal","value":1234,"text":"1234e0"}}`, err: "query has the text \"1234e0\" which is not the number 1234"},
+		{json: `{"version":1,"query":{"type":"literal","value":"a","text":"01"}}`, err: "query has a text but no number value"},
+		{json: `{"version":1,"query":{"type":"call","name":"nope","arguments":[]}}`, err: "illegal query party on 1: nope() calls an unknown function"},
+		{json: `{"version":1,"query":{"type":"macro","name":"isEU"}}`, err: "query references the unknown macro @isEU"},
+		{json: `{"version":1,"query":{"type":"compare","operator":"=","left":{"type":"key","name":"a"},"right":{"type":"compare","operator":"=","left":{"type":"key","name":"b"},"right":{"type":"literal","value":1}}}}`, err: ""},
+		{json: `{"version":1,"query":{"type":"calc","operator":"+","left":{"type":"key","name":"a"},"right":{"type":"literal","value":1}}}`, err: "illegal query party"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := UnmarshalQuery([]byte(testCase.json))
+		if testCase.err == "" {
+			assert.NoError(t, err, testCase.json)
+			continue
+		}
+		if assert.Error(t, err, testCase.json) {
+			assert.Contains(t, err.Error(), testCase.err, testCase.json)
+		}
+	}
+}
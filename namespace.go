@@ -0,0 +1,135 @@
+package simplequery
+
+import (
+	"context"
+	"strings"
+)
+
+// Namespaces is a DataSource which selects the data source by the namespace
+// of a key, instance:amount is the key amount of the instance data source.
+// Keys without namespace are looked up in the data source with the empty name.
+type Namespaces map[string]DataSource
+
+// Lookup returns the value of the key in the data source of its namespace.
+func (n Namespaces) Lookup(key string) (Value, bool) {
+	namespace, name := splitNamespace(key)
+
+	data, ok := n[namespace]
+	if !ok || data == nil {
+		return Value{}, false
+	}
+	return data.Lookup(name)
+}
+
+// LookupContext returns the value of the key like Lookup, the context is passed
+// to a ContextDataSource.
+func (n Namespaces) LookupContext(ctx context.Context, key string) (Value, bool, error) {
+	namespace, name := splitNamespace(key)
+
+	switch data := n[namespace].(type) {
+	case nil:
+		return Value{}, false, nil
+	case ContextDataSource:
+		return data.LookupContext(ctx, name)
+	default:
+		value, ok := data.Lookup(name)
+		return value, ok, nil
+	}
+}
+
+// ScanPrefix calls fn with the keys starting with the prefix in the data source
+// of the namespace of the prefix, if the data source is a KeyScanner.
+func (n Namespaces) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	namespace, name := splitNamespace(prefix)
+	scanner, ok := n[namespace].(KeyScanner)
+	if !ok {
+		return
+	}
+	scanner.ScanPrefix(name, func(key string, value Value) bool {
+		if namespace != "" {
+			key = namespace + ":" + key
+		}
+		return fn(key, value)
+	})
+}
+
+// splitNamespace splits a key at the first colon into namespace and name.
+func splitNamespace(key string) (string, string) {
+	namespace, name, ok := strings.Cut(key, ":")
+	if !ok {
+		return "", key
+	}
+	return namespace, name
+}
+
+// resolveNamespaces checks the namespaces of the keys and adds the default
+// namespace to keys without namespace. Keys in the condition of a quantifier
+// over a list are fields of the items and have no namespace. The keys of a
+// macro are resolved when the library is compiled, they must have a namespace
+// of the query.
+func (p *parser) resolveNamespaces(root node, o *options) error {
+	return walkNamespaced(root, nil, func(n *keyNode, ref *macroNode) error {
+		namespace, _, ok := strings.Cut(n.name, ":")
+		if ref != nil {
+			switch {
+			case !ok:
+				return p.illegalNode(ref, "uses the key "+n.name+" without namespace")
+			case !o.namespaces[namespace]:
+				return p.illegalNode(ref, "uses the key "+n.name+" with an unknown namespace "+namespace)
+			}
+			return nil
+		}
+
+		if !ok {
+			if o.defaultNs == "" {
+				return p.illegalNode(n, "has no namespace")
+			}
+			n.name = o.defaultNs + ":" + n.name
+			return nil
+		}
+		if !o.namespaces[namespace] {
+			return p.illegalNode(n, "has an unknown namespace "+namespace)
+		}
+		return nil
+	})
+}
+
+// walkNamespaced calls fn with the keys which have a namespace and the
+// reference of the macro which contains the key, nil in the query itself.
+func walkNamespaced(n node, ref *macroNode, fn func(*keyNode, *macroNode) error) error {
+	switch n := n.(type) {
+	case *keyNode:
+		return fn(n, ref)
+	case *isNode:
+		return fn(n.key, ref)
+	case *quantifierNode:
+		if n.wildcard {
+			return walkNamespaced(n.x, ref, fn)
+		}
+		return fn(n.key, ref)
+	case *binaryNode:
+		if err := walkNamespaced(n.left, ref, fn); err != nil {
+			return err
+		}
+		return walkNamespaced(n.right, ref, fn)
+	case *notNode:
+		return walkNamespaced(n.x, ref, fn)
+	case *negNode:
+		return walkNamespaced(n.x, ref, fn)
+	case *groupNode:
+		return walkNamespaced(n.x, ref, fn)
+	case *callNode:
+		for _, arg := range n.args {
+			if err := walkNamespaced(arg, ref, fn); err != nil {
+				return err
+			}
+		}
+	case *macroNode:
+		if ref == nil {
+			ref = n
+		}
+		return walkNamespaced(n.macro.root, ref, fn)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package simplequery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	t.Parallel()
+
+	data := Namespaces{
+		"instance": AnyMap{"amount": 120, "customer": map[string]any{"country": "DE"}},
+		"env":      StringMap{"region": "eu"},
+		"":         StringMap{"plain": "yes"},
+	}
+
+	testCases := []struct {
+		key   string
+		value Value
+		found bool
+	}{
+		{key: "instance:amount", value: NumberValue(120), found: true},
+		{key: "env:region", value: StringValue("eu"), found: true},
+		{key: "plain", value: StringValue("yes"), found: true},
+		{key: "env:amount"},
+		{key: "user:role"},
+		{key: "amount"},
+	}
+
+	for _, testCase := range testCases {
+		value, found := data.Lookup(testCase.key)
+		assert.Equal(t, testCase.found, found, testCase.key)
+		if testCase.found {
+			assert.Equal(t, testCase.value, value, testCase.key)
+		}
+	}
+
+	value, found := lookup(data, "instance:customer.country")
+	assert.True(t, found)
+	assert.Equal(t, StringValue("DE"), value)
+}
+
+func TestMatchNamespaces(t *testing.T) {
+	t.Parallel()
+
+	data := Namespaces{
+		"instance": AnyMap{"amount": 120, "items": []any{map[string]any{"sku": "X1"}}},
+		"env":      StringMap{"region": "eu"},
+		"user":     StringMap{"role": "admin"},
+	}
+	opts := []Option{WithNamespaces("instance", "env", "user")}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "amount > 100", ok: true},
+		{query: "instance:amount > 100 AND env:region = eu", ok: true},
+		{query: "user:role = admin AND !user:amount", ok: true},
+		{query: "env:region IS NOT EMPTY", ok: true},
+		{query: "len(env:region) = 2", ok: true},
+		{query: `ANY items: sku = "X1"`, ok: true},
+		{query: "region", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := MatchSource(testCase.query, data, opts...)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	_, err := Compile("amount > 1 AND team:name = a", opts...)
+	assert.EqualError(t, err, "illegal query party on 16: team:name has an unknown namespace team")
+
+	_, err = Compile("env:region AND amount", WithNamespaces("", "env"))
+	assert.EqualError(t, err, "illegal query party on 16: amount has no namespace")
+
+	// the keys of macros must have a namespace of the query
+	library, err := NewLibrary(map[string]string{"big": "amount > 100", "eu": "env:region = eu AND @big"}, WithNamespaces("instance", "env"))
+	assert.NoError(t, err)
+	ok, _, err := MatchSource("@eu AND instance:amount < 200", data, append(opts, WithLibrary(library))...)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = Compile("@eu AND instance:amount > 100", WithNamespaces("instance"), WithLibrary(library))
+	assert.EqualError(t, err, "illegal query party on 1: @eu uses the key env:region with an unknown namespace env")
+
+	plain, err := NewLibrary(map[string]string{"big": "amount > 100"})
+	assert.NoError(t, err)
+	_, err = Compile("@big AND instance:amount > 100", WithNamespaces("instance"), WithLibrary(plain))
+	assert.EqualError(t, err, "illegal query party on 1: @big uses the key amount without namespace")
+
+	// without namespaces the namespace is part of the key
+	ok, _, err = Match("q:variableName", map[string]string{"q:variableName": "x"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
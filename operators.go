@@ -0,0 +1,117 @@
+package simplequery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Precedence of the binary operators. An operator with a higher precedence
+// binds stronger, so a+b*c is a+(b*c).
+const (
+	PrecedenceCompare = 10
+	PrecedenceSum     = 20
+	PrecedenceProduct = 30
+)
+
+// Operator is a custom comparison operator like @> or geo.within.
+type Operator struct {
+	// Precedence of the operator between PrecedenceCompare and PrecedenceProduct.
+	// Zero is PrecedenceCompare, so the operator binds like =.
+	Precedence int
+	// Compare resolves the operator. An error is returned as *EvalError.
+	Compare func(left Value, right Value) (bool, error)
+}
+
+// OperatorRegistry is a set of custom operators which can be used in queries.
+type OperatorRegistry struct {
+	operators map[string]*Operator
+	// symbols are sorted by length, so the longest operator matches first
+	symbols []string
+}
+
+// NewOperatorRegistry creates an empty registry.
+func NewOperatorRegistry() *OperatorRegistry {
+	return &OperatorRegistry{operators: map[string]*Operator{}}
+}
+
+// Register adds an operator to the registry. The symbol is either a word of
+// letters and dots like geo.within, which is matched case-insensitively, or a
+// sequence of special characters like @>. Built-in operators and keywords can
+// not be registered, nor symbols which would be read instead of a placeholder
+// or a macro: symbols with a ?, starting or ending with : or ending with @.
+func (r *OperatorRegistry) Register(symbol string, op Operator) error {
+	if !isWordOperator(symbol) && !isSymbolOperator(symbol) {
+		return fmt.Errorf("invalid operator %q", symbol)
+	}
+	if strings.Contains(symbol, "?") || strings.HasPrefix(symbol, ":") || strings.HasSuffix(symbol, ":") || strings.HasSuffix(symbol, "@") {
+		return fmt.Errorf("operator %q clashes with placeholders or macros", symbol)
+	}
+	if _, ok := keywords[strings.ToUpper(symbol)]; ok {
+		return fmt.Errorf("operator %q is a keyword", symbol)
+	}
+	for _, tok := range tokens {
+		if tok == symbol {
+			return fmt.Errorf("operator %q is built-in", symbol)
+		}
+	}
+	if _, ok := r.lookup(symbol); ok {
+		return fmt.Errorf("operator %q is already registered", symbol)
+	}
+	if op.Compare == nil {
+		return fmt.Errorf("operator %q has no compare", symbol)
+	}
+	if op.Precedence == 0 {
+		op.Precedence = PrecedenceCompare
+	}
+	if op.Precedence < PrecedenceCompare || op.Precedence > PrecedenceProduct {
+		return fmt.Errorf("operator %q has an invalid precedence %d", symbol, op.Precedence)
+	}
+
+	if isWordOperator(symbol) {
+		symbol = strings.ToLower(symbol)
+	}
+	r.operators[symbol] = &op
+	r.symbols = append(r.symbols, symbol)
+	sort.SliceStable(r.symbols, func(i, j int) bool {
+		return len(r.symbols[i]) > len(r.symbols[j])
+	})
+
+	return nil
+}
+
+func (r *OperatorRegistry) lookup(symbol string) (*Operator, bool) {
+	if r == nil {
+		return nil, false
+	}
+
+	if isWordOperator(symbol) {
+		symbol = strings.ToLower(symbol)
+	}
+	op, ok := r.operators[symbol]
+	return op, ok
+}
+
+// isWordOperator reports whether the symbol starts with a letter and consists of letters and dots.
+func isWordOperator(symbol string) bool {
+	for i, r := range symbol {
+		if !unicode.IsLetter(r) && (i == 0 || r != '.') {
+			return false
+		}
+	}
+
+	return symbol != "" && !strings.HasSuffix(symbol, ".")
+}
+
+// isSymbolOperator reports whether the symbol consists of special characters,
+// which are not used for brackets, texts or lists.
+func isSymbolOperator(symbol string) bool {
+	for _, r := range symbol {
+		if !unicode.IsPunct(r) && !unicode.IsSymbol(r) || strings.ContainsRune(`()"',`, r) {
+			return false
+		}
+	}
+
+	return symbol != ""
+}
@@ -0,0 +1,140 @@
+package simplequery
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOperators(t *testing.T) *OperatorRegistry {
+	registry := NewOperatorRegistry()
+
+	assert.NoError(t, registry.Register("@>", Operator{
+		Compare: func(left Value, right Value) (bool, error) {
+			return strings.Contains(left.String(), right.String()), nil
+		},
+	}))
+	assert.NoError(t, registry.Register("^=", Operator{
+		Compare: func(left Value, right Value) (bool, error) {
+			return strings.HasPrefix(left.String(), right.String()), nil
+		},
+	}))
+	assert.NoError(t, registry.Register("geo.within", Operator{
+		Precedence: PrecedenceCompare + 5,
+		Compare: func(left Value, right Value) (bool, error) {
+			if right.String() == "nowhere" {
+				return false, errors.New("unknown area")
+			}
+			return left.String() == "hamburg" && right.String() == "germany", nil
+		},
+	}))
+
+	return registry
+}
+
+func TestOperatorRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := testOperators(t)
+	data := map[string]string{"tags": "a,b,c", "city": "hamburg", "area": "germany", "amount": "10"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "tags @> b", ok: true},
+		{query: "tags @> \"x\"", ok: false},
+		{query: "tags@>b AND tags^=a", ok: true},
+		{query: "!tags @> x", ok: true},
+		{query: "amount + 5 @> 15", ok: true},
+		{query: "city geo.within germany", ok: true},
+		{query: "city GEO.WITHIN \"germany\" OR false", ok: true},
+		{query: "city geo.within area = false", ok: true},
+		{query: "lower(\"HAMBURG\") geo.within germany = true", ok: true},
+		{query: "(city geo.within germany) @> true", ok: true},
+		{query: "missingKey @> b", ok: false},
+		{query: "!missingKey @> b", ok: true},
+		{query: "tags=\"a,b,c\"", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data, WithOperators(registry))
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	result, _, err := Evaluate("missingKey @> b", data, WithOperators(registry), WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+
+	// placeholders are read next to operators
+	q, err := Compile("tags @> ? AND tags^=:prefix", WithOperators(registry))
+	assert.NoError(t, err)
+	named, positional := q.Params()
+	assert.Equal(t, []string{"prefix"}, named)
+	assert.Equal(t, 1, positional)
+
+	// operators are unknown without the registry
+	_, err = Compile("tags @> b")
+	assert.Error(t, err)
+
+	for _, query := range []string{"tags @>", "@> b", "tags @> b @> c", "tags @> (a AND b)", "amount + (tags @> b) > 1", "city geo.within"} {
+		_, err := Compile(query, WithOperators(registry))
+		assert.Error(t, err, query)
+	}
+
+	_, _, err = Match("city geo.within nowhere", data, WithOperators(registry))
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "city geo.within nowhere", evalErr.Text)
+	}
+}
+
+func TestOperatorRegistryRegister(t *testing.T) {
+	t.Parallel()
+
+	compare := func(left Value, right Value) (bool, error) { return true, nil }
+	registry := NewOperatorRegistry()
+
+	assert.NoError(t, registry.Register("~=", Operator{Compare: compare}))
+	assert.NoError(t, registry.Register("like", Operator{Compare: compare, Precedence: PrecedenceProduct}))
+	assert.Error(t, registry.Register("~=", Operator{Compare: compare}))
+	assert.Error(t, registry.Register("LIKE", Operator{Compare: compare}))
+	assert.Error(t, registry.Register(">=", Operator{Compare: compare}))
+	assert.Error(t, registry.Register("and", Operator{Compare: compare}))
+	assert.Error(t, registry.Register("", Operator{Compare: compare}))
+	assert.Error(t, registry.Register("a b", Operator{Compare: compare}))
+	assert.Error(t, registry.Register("geo.", Operator{Compare: compare}))
+	assert.Error(t, registry.Register("@(", Operator{Compare: compare}))
+	for _, symbol := range []string{"?", "?=", ">?", ":", ":=", "=:", "@", ">@"} {
+		assert.EqualError(t, registry.Register(symbol, Operator{Compare: compare}), `operator "`+symbol+`" clashes with placeholders or macros`, symbol)
+	}
+	assert.Error(t, registry.Register("noCompare", Operator{}))
+	assert.Error(t, registry.Register("low", Operator{Compare: compare, Precedence: 1}))
+	assert.Error(t, registry.Register("high", Operator{Compare: compare, Precedence: PrecedenceProduct + 1}))
+
+	assert.Equal(t, []string{"like", "~="}, registry.symbols)
+}
+
+func TestLexOperator(t *testing.T) {
+	t.Parallel()
+
+	lexer := NewLexer("a@>b ^= c geo.within d GEO.WITHIN geo.withinx")
+	lexer.operators = testOperators(t)
+
+	tokens := []Token{}
+	texts := []string{}
+	for {
+		_, tok, text := lexer.Lex()
+		tokens = append(tokens, tok)
+		texts = append(texts, text)
+		if tok == EOF {
+			break
+		}
+	}
+
+	assert.Equal(t, []Token{IDENT, OPERATOR, IDENT, OPERATOR, IDENT, OPERATOR, IDENT, OPERATOR, IDENT, EOF}, tokens)
+	assert.Equal(t, []string{"a", "@>", "b", "^=", "c", "geo.within", "d", "GEO.WITHIN", "geo.withinx", ""}, texts)
+}
@@ -0,0 +1,94 @@
+package simplequery
+
+// Option changes how a query is compiled or matched. Options for the
+// compilation are ignored by the Match and Evaluate methods of a compiled query.
+type Option func(*options)
+
+type options struct {
+	truthiness bool
+	unknown    bool
+	full       bool
+	limits     Limits
+	library    *Library
+	functions  *FunctionRegistry
+	operators  *OperatorRegistry
+	namespaces map[string]bool
+	defaultNs  string
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{functions: defaultFunctions}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithTruthiness lets a bare key match only if it exists and its value is truthy,
+// e.g. "1", "yes" or "true". Without this option a bare key only has to exist.
+func WithTruthiness() Option {
+	return func(o *options) {
+		o.truthiness = true
+	}
+}
+
+// WithUnknown enables SQL-style three-valued logic. A comparison on a missing key
+// is UNKNOWN instead of false and UNKNOWN propagates through AND, OR and NOT,
+// so !amount>5 does not match if amount is missing.
+func WithUnknown() Option {
+	return func(o *options) {
+		o.unknown = true
+	}
+}
+
+// WithFullEvaluation evaluates both sides of AND and OR, e.g. for audits, so
+// every condition of the explanation has a result. Without this option the
+// right side is skipped if the left side decides the result.
+func WithFullEvaluation() Option {
+	return func(o *options) {
+		o.full = true
+	}
+}
+
+// WithLimits restricts the resources of the query, see Limits. The size limits
+// are used when the query is compiled, the list and step limits when it is matched.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithFunctions makes the functions of the registry available to the query,
+// a nil registry has only the built-in functions. It is used when the query is compiled.
+func WithFunctions(registry *FunctionRegistry) Option {
+	return func(o *options) {
+		o.functions = registry
+	}
+}
+
+// WithOperators makes the custom operators of the registry available to the query.
+// It is used when the query is compiled.
+func WithOperators(registry *OperatorRegistry) Option {
+	return func(o *options) {
+		o.operators = registry
+	}
+}
+
+// WithNamespaces declares the namespaces of the keys, e.g. instance:amount or
+// env:region. A key without namespace belongs to the default namespace, with an
+// empty default namespace every key needs a namespace. A key with an unknown
+// namespace is an error. It is used when the query is compiled, the data is
+// passed as Namespaces.
+func WithNamespaces(defaultNamespace string, namespaces ...string) Option {
+	return func(o *options) {
+		o.defaultNs = defaultNamespace
+		o.namespaces = map[string]bool{}
+		if defaultNamespace != "" {
+			o.namespaces[defaultNamespace] = true
+		}
+		for _, namespace := range namespaces {
+			o.namespaces[namespace] = true
+		}
+	}
+}
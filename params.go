@@ -0,0 +1,152 @@
+package simplequery
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ErrUnboundParam is returned if a placeholder has no value. Bind returns it
+// for a missing value, Match as *EvalError for a query which is not bound.
+var ErrUnboundParam = errors.New("parameter is not bound")
+
+// Params returns the names of the named placeholders like :threshold in the
+// order of the query and the number of positional placeholders ?. Bind needs
+// a value for each of them.
+func (q *Query) Params() (named []string, positional int) {
+	named = []string{}
+	seen := map[string]bool{}
+	walkParams(q.root, func(n *paramNode) {
+		if n.name == "" {
+			positional++
+		} else if !seen[n.name] {
+			seen[n.name] = true
+			named = append(named, n.name)
+		}
+	})
+
+	return named, positional
+}
+
+// Bind returns a copy of the query with the values of the placeholders. A
+// named placeholder takes the value of its name, which may be used more than
+// once, the positional placeholders take the values in order. The values are
+// converted with ValueOf. Values of names which are not used are ignored.
+func (q *Query) Bind(named map[string]any, positional ...any) (*Query, error) {
+	_, count := q.Params()
+	if len(positional) > count {
+		return nil, fmt.Errorf("%d positional parameters for %d placeholders", len(positional), count)
+	}
+
+	root, err := bindParams(q.root, named, positional)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Query{source: q.source, root: root, keys: q.keys}, nil
+}
+
+// bindParams copies the nodes with placeholders and replaces the placeholders
+// with literals.
+func bindParams(n node, named map[string]any, positional []any) (node, error) {
+	switch n := n.(type) {
+	case *paramNode:
+		var value any
+		var ok bool
+		if n.name == "" {
+			if ok = n.index <= len(positional); ok {
+				value = positional[n.index-1]
+			}
+		} else {
+			value, ok = named[n.name]
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnboundParam, n.paramName())
+		}
+		return &literalNode{Span: n.Span, value: ValueOf(value)}, nil
+	case *binaryNode:
+		left, err := bindParams(n.left, named, positional)
+		if err != nil {
+			return nil, err
+		}
+		right, err := bindParams(n.right, named, positional)
+		if err != nil {
+			return nil, err
+		}
+
+		bound := *n
+		bound.left, bound.right = left, right
+		return &bound, nil
+	case *notNode:
+		x, err := bindParams(n.x, named, positional)
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{Span: n.Span, x: x}, nil
+	case *negNode:
+		x, err := bindParams(n.x, named, positional)
+		if err != nil {
+			return nil, err
+		}
+		return &negNode{Span: n.Span, x: x}, nil
+	case *groupNode:
+		x, err := bindParams(n.x, named, positional)
+		if err != nil {
+			return nil, err
+		}
+		return &groupNode{Span: n.Span, x: x}, nil
+	case *quantifierNode:
+		x, err := bindParams(n.x, named, positional)
+		if err != nil {
+			return nil, err
+		}
+
+		bound := *n
+		bound.x = x
+		return &bound, nil
+	case *callNode:
+		bound := *n
+		bound.args = make([]node, len(n.args))
+		for i, arg := range n.args {
+			x, err := bindParams(arg, named, positional)
+			if err != nil {
+				return nil, err
+			}
+			bound.args[i] = x
+		}
+		return &bound, nil
+	}
+
+	return n, nil
+}
+
+// walkParams calls fn with the placeholders in the order of the query.
+func walkParams(n node, fn func(*paramNode)) {
+	switch n := n.(type) {
+	case *paramNode:
+		fn(n)
+	case *binaryNode:
+		walkParams(n.left, fn)
+		walkParams(n.right, fn)
+	case *notNode:
+		walkParams(n.x, fn)
+	case *negNode:
+		walkParams(n.x, fn)
+	case *groupNode:
+		walkParams(n.x, fn)
+	case *quantifierNode:
+		walkParams(n.x, fn)
+	case *callNode:
+		for _, arg := range n.args {
+			walkParams(arg, fn)
+		}
+	}
+}
+
+// paramName returns the placeholder as written in the query.
+func (n *paramNode) paramName() string {
+	if n.name == "" {
+		return "?" + strconv.Itoa(n.index)
+	}
+	return ":" + n.name
+}
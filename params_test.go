@@ -0,0 +1,116 @@
+package simplequery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLexParam(t *testing.T) {
+	t.Parallel()
+
+	lexer := NewLexer("amount > :threshold AND region=:region_1 OR ? items: x")
+
+	texts := []string{}
+	tokens := []Token{}
+	for {
+		_, tok, text := lexer.Lex()
+		tokens = append(tokens, tok)
+		texts = append(texts, text)
+		if tok == EOF {
+			break
+		}
+	}
+
+	assert.Equal(t, []Token{IDENT, GT, PARAM, AND, IDENT, EQ, PARAM, OR, PARAM, IDENT, COLON, IDENT, EOF}, tokens)
+	assert.Equal(t, []string{"amount", ">", "threshold", "AND", "region", "=", "region_1", "OR", "?", "items", ":", "x", ""}, texts)
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	q, err := Compile("amount > :threshold AND region = :region AND lower(name) = ? AND (:threshold < 500 OR ?)")
+	assert.NoError(t, err)
+
+	named, positional := q.Params()
+	assert.Equal(t, []string{"threshold", "region"}, named)
+	assert.Equal(t, 2, positional)
+
+	data := map[string]string{"amount": "150", "region": "north", "name": "Anna"}
+
+	// an unbound query fails at the first placeholder
+	ok, explanation, err := q.Match(data)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, ErrUnboundParam)
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, ":threshold", evalErr.Text)
+	}
+	assert.NotNil(t, explanation)
+
+	bound, err := q.Bind(map[string]any{"threshold": 100, "region": "north", "unused": 1}, "anna", false)
+	assert.NoError(t, err)
+
+	ok, _, err = bound.Match(data)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	named, positional = bound.Params()
+	assert.Empty(t, named)
+	assert.Equal(t, 0, positional)
+
+	// the compiled query is not changed
+	bound, err = q.Bind(map[string]any{"threshold": 200, "region": "north"}, "anna", false)
+	assert.NoError(t, err)
+	ok, _, err = bound.Match(data)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	testCases := []struct {
+		named      map[string]any
+		positional []any
+		err        string
+	}{
+		{named: map[string]any{"region": "north"}, positional: []any{"anna", false}, err: "parameter is not bound: :threshold"},
+		{named: map[string]any{"threshold": 1, "region": "north"}, positional: []any{"anna"}, err: "parameter is not bound: ?2"},
+		{named: map[string]any{"threshold": 1, "region": "north"}, positional: []any{"anna", false, 1}, err: "3 positional parameters for 2 placeholders"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := q.Bind(testCase.named, testCase.positional...)
+		assert.EqualError(t, err, testCase.err)
+	}
+}
+
+func TestBindTypes(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		query string
+		value any
+		ok    bool
+	}{
+		{query: "amount > ?", value: 100, ok: true},
+		{query: "amount > ?", value: "100", ok: true},
+		{query: "amount = ?", value: 150.0, ok: true},
+		{query: "created > ?", value: "2024-01-01", ok: true},
+		{query: "name = ?", value: "a b \"c\"", ok: true},
+		{query: "?", value: true, ok: true},
+		{query: "? AND amount", value: false, ok: false},
+		{query: "nokey = ?", value: nil, ok: true},
+	}
+
+	data := map[string]string{"amount": "150", "created": "2024-03-01", "name": "a b \"c\""}
+	for _, testCase := range testCases {
+		q, err := Compile(testCase.query)
+		assert.NoError(t, err, testCase.query)
+
+		bound, err := q.Bind(nil, testCase.value)
+		assert.NoError(t, err, testCase.query)
+
+		ok, _, err := bound.Match(data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+}
@@ -0,0 +1,763 @@
+package simplequery
+
+import (
+	"fmt"
+)
+
+// Span is a range of bytes in the query, End is exclusive.
+type Span struct {
+	Start int
+	End   int
+}
+
+func (s Span) span() Span {
+	return s
+}
+
+func join(from Span, to Span) Span {
+	return Span{Start: from.Start, End: to.End}
+}
+
+// node is an element of the parsed query tree.
+type node interface {
+	span() Span
+}
+
+// binaryNode joins two nodes with AND, OR, a comparison or an arithmetic operator.
+// A custom operator has the OPERATOR token and the registered operator.
+type binaryNode struct {
+	Span
+	op       Token
+	left     node
+	right    node
+	symbol   string
+	operator *Operator
+}
+
+// notNode negates its operand.
+type notNode struct {
+	Span
+	x node
+}
+
+// negNode is the unary minus of a number.
+type negNode struct {
+	Span
+	x node
+}
+
+// groupNode is a bracketed sub-query or a bracketed calculation.
+type groupNode struct {
+	Span
+	x node
+}
+
+// keyNode references a key of the data set.
+type keyNode struct {
+	Span
+	name string
+}
+
+// isNode tests a key with IS [NOT] EMPTY, MISSING, BLANK or NULL or whether it EXISTS.
+type isNode struct {
+	Span
+	key       *keyNode
+	negate    bool
+	predicate Token
+}
+
+// quantifierNode tests a condition against each item of a list with ANY, ALL or NONE.
+// With wildcard the condition is tested with each key matching the wildcard key.
+type quantifierNode struct {
+	Span
+	quantifier Token
+	key        *keyNode
+	x          node
+	wildcard   bool
+}
+
+// callNode calls a function with its arguments.
+type callNode struct {
+	Span
+	name string
+	args []node
+	fn   *Function
+}
+
+// paramNode is a placeholder for a value which is bound before the query is
+// matched, a named :name or the index of a positional ? starting at 1.
+type paramNode struct {
+	Span
+	name  string
+	index int
+}
+
+// macroNode references a macro of a library.
+type macroNode struct {
+	Span
+	macro *macro
+}
+
+// literalNode is a constant value.
+type literalNode struct {
+	Span
+	value Value
+}
+
+type item struct {
+	pos  int
+	tok  Token
+	text string
+	span Span
+}
+
+type parser struct {
+	lexer     *Lexer
+	functions *FunctionRegistry
+	operators *OperatorRegistry
+	limits    Limits
+	library   *Library
+	depth     int
+	params    int
+	cur       item
+	// ahead is the token after cur if it was read by peek
+	ahead *item
+}
+
+// parse reads all tokens of the lexer and builds the query tree.
+func parse(lexer *Lexer, o *options) (node, error) {
+	n, p, err := parseExpression(lexer, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.checkCondition(n); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+// parseExpression builds the tree of a condition or a value.
+func parseExpression(lexer *Lexer, o *options) (node, *parser, error) {
+	lexer.operators = o.operators
+	lexer.library = o.library
+	if err := o.limits.checkBytes(lexer.input); err != nil {
+		return nil, nil, err
+	}
+
+	p := &parser{lexer: lexer, functions: o.functions, operators: o.operators, limits: o.limits, library: o.library}
+	p.next()
+
+	n, err := p.parseLogical()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if p.cur.tok != EOF {
+		return nil, nil, p.illegal()
+	}
+
+	if err := o.limits.checkNodes(n); err != nil {
+		return nil, nil, err
+	}
+
+	if o.namespaces != nil {
+		if err := p.resolveNamespaces(n, o); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return n, p, nil
+}
+
+func (p *parser) next() {
+	if p.ahead != nil {
+		p.cur, p.ahead = *p.ahead, nil
+		return
+	}
+
+	pos, tok, text := p.lexer.Lex()
+	p.cur = item{pos: pos, tok: tok, text: text, span: p.lexer.span()}
+}
+
+// peek returns the token after the current one.
+func (p *parser) peek() item {
+	if p.ahead == nil {
+		pos, tok, text := p.lexer.Lex()
+		p.ahead = &item{pos: pos, tok: tok, text: text, span: p.lexer.span()}
+	}
+	return *p.ahead
+}
+
+// word returns the current token as key if it is a keyword which is only
+// a keyword in its place, e.g. EMPTY after IS. Elsewhere it is a key like
+// status or the text on the right side of status=empty.
+func (p *parser) word() item {
+	if !isWord(p.cur.tok) {
+		return p.cur
+	}
+
+	cur := p.cur
+	cur.tok, cur.text = IDENT, p.lexer.input[cur.span.Start:cur.span.End]
+	return cur
+}
+
+// isPrefix reports whether the current keyword is followed by an operand,
+// e.g. NOT in NOT a, and is no key like in not = 1.
+func (p *parser) isPrefix() bool {
+	next := p.peek()
+	switch next.tok {
+	case EOF, BRACKET_RIGHT, COMMA, COLON, AND, OR, IS:
+		return false
+	}
+
+	precedence, _ := p.precedence(next)
+	return precedence == 0
+}
+
+func (p *parser) illegal() error {
+	return fmt.Errorf("illegal query party %s on %d: %s", p.cur.tok.String(), p.cur.pos, p.cur.text)
+}
+
+// illegalNode reports a node which is not allowed at its place.
+func (p *parser) illegalNode(n node, reason string) error {
+	s := n.span()
+	return fmt.Errorf("illegal query party on %d: %s %s", s.Start+1, p.lexer.input[s.Start:s.End], reason)
+}
+
+// parseLogical reads conditions joined with AND and OR. Both bind the same,
+// the conditions are grouped from left to right: a OR b AND c is
+// (a OR b) AND c.
+func (p *parser) parseLogical() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		// conditions next to each other are joined with AND
+		op := Token(AND)
+		if p.cur.tok == AND || p.cur.tok == OR {
+			op = p.cur.tok
+			p.next()
+		} else if !isConditionStart(p.cur.tok) {
+			return left, nil
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &binaryNode{Span: join(left.span(), right.span()), op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.cur.tok == EXISTS && p.isPrefix() {
+		return p.parseExists()
+	}
+	if p.cur.tok != N && p.cur.tok != NOT && p.cur.tok != ANY && p.cur.tok != ALL && p.cur.tok != NONE {
+		return p.parseComparison()
+	}
+	if p.cur.tok != N && !p.isPrefix() {
+		return p.parseComparison()
+	}
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	if p.cur.tok == ANY || p.cur.tok == ALL || p.cur.tok == NONE {
+		return p.parseQuantifier()
+	}
+
+	start := p.cur.span
+	p.next()
+
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	return &notNode{Span: join(start, x.span()), x: x}, nil
+}
+
+// parseQuantifier reads ANY, ALL or NONE, the key of the list, a colon and the
+// condition for the items, or a condition with a wildcard key.
+func (p *parser) parseQuantifier() (node, error) {
+	start := p.cur
+	p.next()
+
+	x, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := x.(*keyNode); ok && p.cur.tok == COLON {
+		p.next()
+
+		items, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &quantifierNode{Span: join(start.span, items.span()), quantifier: start.tok, key: key, x: items}, nil
+	}
+
+	keys := wildcardKeys(x)
+	if len(keys) == 0 {
+		return nil, p.illegal()
+	}
+
+	return &quantifierNode{Span: join(start.span, x.span()), quantifier: start.tok, key: keys[0], x: x, wildcard: true}, nil
+}
+
+// parseExists reads EXISTS and the key.
+func (p *parser) parseExists() (node, error) {
+	start := p.cur.span
+	p.next()
+
+	cur := p.word()
+	if cur.tok != IDENT {
+		return nil, p.illegal()
+	}
+	key := &keyNode{Span: cur.span, name: cur.text}
+	p.next()
+
+	return &isNode{Span: join(start, key.Span), key: key, predicate: EXISTS}, nil
+}
+
+// parseComparison reads a comparison, a calculation or an IS predicate.
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseBinary(PrecedenceCompare)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cur.tok == IS {
+		return p.parseIs(left)
+	}
+
+	return left, nil
+}
+
+// parseBinary reads operands joined with binary operators of at least the
+// given precedence. Comparisons of the same precedence can not be chained.
+func (p *parser) parseBinary(precedence int) (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	last := 0
+	for {
+		op := p.cur
+		opPrecedence, custom := p.precedence(op)
+		if opPrecedence == 0 || opPrecedence < precedence {
+			return left, nil
+		}
+
+		comparison := isOperator(op.tok) || custom != nil
+		if comparison && opPrecedence == last {
+			return left, nil
+		}
+		p.next()
+
+		// a comparison without right side at the end of a condition compares
+		// with an empty text, e.g. key= or key!=
+		var right node = &literalNode{Span: Span{Start: op.span.End, End: op.span.End}, value: StringValue("")}
+		if !isOperator(op.tok) || !isStopToken(p.cur.tok) {
+			right, err = p.parseBinary(opPrecedence + 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if comparison {
+			left, err = p.comparison(op, custom, left, right)
+			last = opPrecedence
+		} else {
+			left, err = p.arithmetic(op.tok, left, right)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// isStopToken reports whether the token ends a condition.
+func isStopToken(token Token) bool {
+	return token == EOF || token == AND || token == OR || token == BRACKET_RIGHT
+}
+
+// precedence of a binary operator, zero if the item is no binary operator.
+func (p *parser) precedence(op item) (int, *Operator) {
+	switch {
+	case isOperator(op.tok):
+		return PrecedenceCompare, nil
+	case op.tok == PLUS || op.tok == MINUS:
+		return PrecedenceSum, nil
+	case op.tok == MUL || op.tok == DIV || op.tok == MOD:
+		return PrecedenceProduct, nil
+	case op.tok == OPERATOR:
+		if custom, ok := p.operators.lookup(op.text); ok {
+			return custom.Precedence, custom
+		}
+	}
+
+	return 0, nil
+}
+
+func (p *parser) comparison(op item, custom *Operator, left node, right node) (node, error) {
+	// a single word on the right side is a text and no key
+	if key, ok := right.(*keyNode); ok {
+		right = &literalNode{Span: key.Span, value: StringValue(key.name)}
+	}
+
+	if err := p.checkOperand(op, custom, left); err != nil {
+		return nil, err
+	}
+	if err := p.checkOperand(op, custom, right); err != nil {
+		return nil, err
+	}
+
+	n := &binaryNode{Span: join(left.span(), right.span()), op: op.tok, left: left, right: right}
+	if custom != nil {
+		n.symbol = op.text
+		n.operator = custom
+	}
+
+	return n, nil
+}
+
+// parseIs reads the predicate after IS.
+func (p *parser) parseIs(left node) (node, error) {
+	key, ok := left.(*keyNode)
+	if !ok {
+		return nil, p.illegalNode(left, "is no key")
+	}
+
+	n := &isNode{key: key}
+	p.next()
+
+	if p.cur.tok == NOT {
+		n.negate = true
+		p.next()
+	}
+
+	switch p.cur.tok {
+	case EMPTY, MISSING, BLANK, NULL:
+		n.predicate = p.cur.tok
+	default:
+		return nil, p.illegal()
+	}
+	n.Span = join(key.Span, p.cur.span)
+	p.next()
+
+	return n, nil
+}
+
+func (p *parser) arithmetic(op Token, left node, right node) (node, error) {
+	if !isValue(left) {
+		return nil, p.illegalNode(left, "is no number")
+	}
+	if !isValue(right) {
+		return nil, p.illegalNode(right, "is no number")
+	}
+
+	return &binaryNode{Span: join(left.span(), right.span()), op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.cur.tok != MINUS {
+		return p.parsePrimary()
+	}
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	start := p.cur.span
+	p.next()
+
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValue(x) {
+		return nil, p.illegalNode(x, "is no number")
+	}
+
+	return &negNode{Span: join(start, x.span()), x: x}, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	cur := p.word()
+
+	switch cur.tok {
+	case BRACKET_LEFT:
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+		p.next()
+
+		x, err := p.parseLogical()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.cur.tok != BRACKET_RIGHT {
+			return nil, p.illegal()
+		}
+		end := p.cur.span
+		p.next()
+
+		return &groupNode{Span: join(cur.span, end), x: x}, nil
+	case TRUE, FALSE:
+		p.next()
+		return &literalNode{Span: cur.span, value: BoolValue(cur.tok == TRUE)}, nil
+	case NULL:
+		p.next()
+		return &literalNode{Span: cur.span, value: NullValue()}, nil
+	case NUMBER:
+		p.next()
+		return &literalNode{Span: cur.span, value: numberLiteral(cur.text)}, nil
+	case STRING:
+		p.next()
+		return &literalNode{Span: cur.span, value: StringValue(cur.text)}, nil
+	case MACRO:
+		return p.parseMacro(cur)
+	case PARAM:
+		p.next()
+		if cur.text == "?" {
+			p.params++
+			return &paramNode{Span: cur.span, index: p.params}, nil
+		}
+		return &paramNode{Span: cur.span, name: cur.text}, nil
+	case IDENT:
+		p.next()
+
+		// a bracket right behind the name calls a function
+		if p.cur.tok == BRACKET_LEFT && p.cur.span.Start == cur.span.End {
+			return p.parseCall(cur)
+		}
+
+		return &keyNode{Span: cur.span, name: cur.text}, nil
+	default:
+		return nil, p.illegal()
+	}
+}
+
+// parseCall reads the arguments of a function call.
+func (p *parser) parseCall(name item) (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	n := &callNode{name: name.text}
+	p.next()
+
+	for p.cur.tok != BRACKET_RIGHT {
+		if len(n.args) > 0 {
+			if p.cur.tok != COMMA {
+				return nil, p.illegal()
+			}
+			p.next()
+		}
+
+		arg, err := p.parseBinary(PrecedenceCompare + 1)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := arg.(*literalNode); !ok && !isValue(arg) {
+			return nil, p.illegalNode(arg, "is no argument")
+		}
+
+		n.args = append(n.args, arg)
+	}
+	n.Span = join(name.span, p.cur.span)
+	p.next()
+
+	fn, ok := p.functions.lookup(n.name)
+	if !ok {
+		return nil, p.illegalNode(n, "calls an unknown function")
+	}
+	if !fn.accepts(len(n.args)) {
+		if fn.Variadic {
+			return nil, p.illegalNode(n, fmt.Sprintf("expects at least %d arguments", len(fn.Params)-1))
+		}
+		return nil, p.illegalNode(n, fmt.Sprintf("expects %d arguments", len(fn.Params)))
+	}
+	for i, arg := range n.args {
+		if kind := fn.param(i); !convertible(arg, kind) {
+			return nil, p.illegalNode(arg, "is no "+kind.String())
+		}
+	}
+	n.fn = fn
+
+	return n, nil
+}
+
+// convertible reports whether the argument can be converted to the kind. The
+// kind of keys is only known when the query is matched.
+func convertible(arg node, kind Kind) bool {
+	switch kind {
+	case KindAny, KindString:
+		return true
+	case KindBool:
+		return !isValue(arg) || staticKind(arg) == KindAny
+	default:
+		// numbers and times depend on the argument
+	}
+
+	switch n := arg.(type) {
+	case *literalNode:
+		_, err := convert(n.value, kind)
+		return err == nil
+	case *callNode:
+		return n.fn.Result != KindBool
+	}
+
+	return isValue(arg)
+}
+
+// staticKind returns the kind of the node which is known before the query is matched.
+func staticKind(n node) Kind {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value.Kind()
+	case *callNode:
+		return n.fn.Result
+	case *negNode:
+		return KindNumber
+	case *binaryNode:
+		if isArithmetic(n.op) {
+			return KindNumber
+		}
+		return KindBool
+	case *groupNode:
+		return staticKind(n.x)
+	case *macroNode:
+		return staticKind(n.macro.root)
+	}
+
+	return KindAny
+}
+
+// checkCondition ensures that the node has a boolean result.
+// A bare key is a condition, it tests whether the key exists.
+func (p *parser) checkCondition(n node) error {
+	keys := wildcardKeys(n)
+	for _, key := range keys {
+		if key.name != keys[0].name {
+			return p.illegalNode(n, "has more than one wildcard key")
+		}
+	}
+
+	switch n := n.(type) {
+	case *binaryNode:
+		if isArithmetic(n.op) {
+			return p.illegalNode(n, "is no condition")
+		}
+		if n.op == AND || n.op == OR {
+			if err := p.checkCondition(n.left); err != nil {
+				return err
+			}
+			return p.checkCondition(n.right)
+		}
+	case *notNode:
+		return p.checkCondition(n.x)
+	case *groupNode:
+		return p.checkCondition(n.x)
+	case *quantifierNode:
+		return p.checkCondition(n.x)
+	case *literalNode:
+		if n.value.Kind() != KindBool {
+			return p.illegalNode(n, "is no condition")
+		}
+	case *negNode:
+		return p.illegalNode(n, "is no condition")
+	case *callNode:
+		if n.fn.Result != KindBool && n.fn.Result != KindAny {
+			return p.illegalNode(n, "is no condition")
+		}
+	case *macroNode:
+		if !n.macro.condition {
+			return p.illegalNode(n, "is no condition")
+		}
+	}
+
+	return nil
+}
+
+// checkOperand ensures that the node is a value which can be compared. Custom
+// operators, = and != also accept literals like TRUE and NULL and conditions.
+func (p *parser) checkOperand(op item, custom *Operator, n node) error {
+	if isValue(n) {
+		return nil
+	}
+
+	if (custom != nil || op.tok == EQ || op.tok == NE) && isOperand(n) {
+		return nil
+	}
+
+	return p.illegalNode(n, "can not be compared with "+op.text)
+}
+
+// isOperand reports whether the node has a value, which is the result for a condition.
+func isOperand(n node) bool {
+	switch n := n.(type) {
+	case *literalNode, *callNode:
+		return true
+	case *binaryNode:
+		return n.op != AND && n.op != OR
+	case *groupNode:
+		return isOperand(n.x)
+	case *macroNode:
+		return isOperand(n.macro.root)
+	}
+
+	return isValue(n)
+}
+
+// isValue reports whether the node is a number or a text, which may be parsed as a number.
+func isValue(n node) bool {
+	switch n := n.(type) {
+	case *keyNode, *negNode, *paramNode:
+		return true
+	case *callNode:
+		return n.fn.Result != KindBool
+	case *literalNode:
+		return n.value.Kind() == KindNumber || n.value.Kind() == KindString
+	case *binaryNode:
+		return isArithmetic(n.op)
+	case *groupNode:
+		return isValue(n.x)
+	case *macroNode:
+		return isValue(n.macro.root)
+	}
+
+	return false
+}
+
+func isArithmetic(token Token) bool {
+	return token == PLUS || token == MINUS || token == MUL || token == DIV || token == MOD
+}
+
+// isWord reports whether the keyword is a key outside of its place.
+func isWord(token Token) bool {
+	return token == IS || token == NOT || token == EMPTY || token == MISSING || token == BLANK ||
+		token == ANY || token == ALL || token == NONE || token == EXISTS
+}
+
+func isConditionStart(token Token) bool {
+	return token == IDENT || isWord(token) && token != IS || token == N || token == BRACKET_LEFT ||
+		token == TRUE || token == FALSE || token == NULL || token == NUMBER || token == MINUS ||
+		token == PARAM || token == MACRO
+}
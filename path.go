@@ -0,0 +1,77 @@
+package simplequery
+
+import (
+	"strconv"
+	"strings"
+)
+
+// lookup returns the value of a key. A key which does not exist as it is, is
+// read as path like customer.address.country or items[0].sku into nested
+// objects and lists.
+func lookup(data DataSource, key string) (Value, bool) {
+	if value, ok := data.Lookup(key); ok || !isPath(key) {
+		return value, ok
+	}
+
+	segments := splitPath(key)
+	value, ok := data.Lookup(segments[0])
+	for _, segment := range segments[1:] {
+		if !ok {
+			return Value{}, false
+		}
+		value, ok = field(value, segment)
+	}
+
+	return value, ok
+}
+
+// field returns a field of an object or, with an [index] segment, an item of a list.
+func field(value Value, segment string) (Value, bool) {
+	if strings.HasPrefix(segment, "[") {
+		index, err := strconv.Atoi(segment[1 : len(segment)-1])
+		items := value.List()
+		if err != nil || value.Kind() != KindList || index >= len(items) {
+			return Value{}, false
+		}
+		return items[index], true
+	}
+
+	fields, ok := value.Object()
+	if !ok {
+		return Value{}, false
+	}
+	return fields.Lookup(segment)
+}
+
+func isPath(key string) bool {
+	return strings.ContainsAny(key, ".[")
+}
+
+// splitPath splits a key at dots and before index brackets, items[0].sku
+// becomes items, [0] and sku.
+func splitPath(key string) []string {
+	segments := []string{}
+	start := 0
+	for i, r := range key {
+		switch r {
+		case '.':
+			if i > start {
+				segments = append(segments, key[start:i])
+			}
+			start = i + 1
+		case '[':
+			if i > start {
+				segments = append(segments, key[start:i])
+			}
+			start = i
+		case ']':
+			segments = append(segments, key[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(key) {
+		segments = append(segments, key[start:])
+	}
+
+	return segments
+}
@@ -0,0 +1,106 @@
+package simplequery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		key      string
+		segments []string
+	}{
+		{key: "amount", segments: []string{"amount"}},
+		{key: "customer.address.country", segments: []string{"customer", "address", "country"}},
+		{key: "items[0].sku", segments: []string{"items", "[0]", "sku"}},
+		{key: "matrix[1][2]", segments: []string{"matrix", "[1]", "[2]"}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.segments, splitPath(testCase.key), testCase.key)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	data := AnyMap{}
+	err := json.Unmarshal([]byte(`{
+		"customer": {"address": {"country": "DE"}, "name": null},
+		"items": [{"sku": "X1", "qty": 3}, {"sku": "X2", "qty": 1}],
+		"matrix": [[1, 2], [3, 4]],
+		"flat.key": "flat",
+		"flat": {"key": "nested"}
+	}`), &data)
+	assert.NoError(t, err)
+	data["typed"] = map[string][]int{"numbers": {5, 6}}
+
+	testCases := []struct {
+		key   string
+		value Value
+		found bool
+	}{
+		{key: "customer.address.country", value: StringValue("DE"), found: true},
+		{key: "customer.name", value: NullValue(), found: true},
+		{key: "items[0].sku", value: StringValue("X1"), found: true},
+		{key: "items[1].qty", value: NumberValue(1), found: true},
+		{key: "matrix[1][0]", value: NumberValue(3), found: true},
+		{key: "flat.key", value: StringValue("flat"), found: true},
+		{key: "typed.numbers[1]", value: NumberValue(6), found: true},
+		{key: "customer.address.city"},
+		{key: "customer.name.first"},
+		{key: "items[2].sku"},
+		{key: "items.sku"},
+		{key: "customer[0]"},
+		{key: "unknownKey.name"},
+	}
+
+	for _, testCase := range testCases {
+		value, found := lookup(data, testCase.key)
+		assert.Equal(t, testCase.found, found, testCase.key)
+		if testCase.found {
+			assert.Equal(t, testCase.value, value, testCase.key)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{}
+	err := json.Unmarshal([]byte(`{
+		"customer": {"address": {"country": "DE", "zip": ""}},
+		"items": [{"sku": "X1", "qty": 3}]
+	}`), &data)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "customer.address.country=DE", ok: true},
+		{query: `items[0].sku="X1" AND items[0].qty > 2`, ok: true},
+		{query: "items[0].qty * 2 = 6", ok: true},
+		{query: "lower(customer.address.country) = de", ok: true},
+		{query: "customer.address", ok: true},
+		{query: "customer.address.city", ok: false},
+		{query: "!customer.address.city", ok: true},
+		{query: "customer.address.zip IS EMPTY", ok: true},
+		{query: "customer.address.city IS MISSING", ok: true},
+		{query: `items[1].sku = "X1"`, ok: false},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := MatchAny(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	ok, _, err := Match("customer.country=DE", map[string]string{"customer.country": "DE"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
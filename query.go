@@ -1,208 +1,752 @@
 package simplequery
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
+// QueryLexer returns the tokens of a query, it is implemented by Lexer.
+//
+// Deprecated: queries are read by Compile, which does not take a QueryLexer.
 type QueryLexer interface {
 	Lex() (position int, token Token, text string)
 }
 
+// Query is a compiled query which can be matched against many data sets.
+type Query struct {
+	source string
+	root   node
+	keys   map[string]bool
+}
+
+// Compile parses the input into a reusable query.
+func Compile(input string, opts ...Option) (*Query, error) {
+	root, err := parse(NewLexer(input), newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	referencedKeys(root, keys)
+
+	return &Query{source: input, root: root, keys: keys}, nil
+}
+
 // Match the input to the data. Returns whether it is a successful match,
-// an array with the individual results and an error if the input query contains errors.
-func Match(input string, data map[string]string) (ok bool, details []bool, err error) {
-	lexer := NewLexer(input)
+// the explanation of the result of each part of the query and an error if the
+// input query contains errors.
+func Match(input string, data map[string]string, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	return MatchSource(input, StringMap(data), opts...)
+}
+
+// MatchSource matches the input like Match, but looks up the keys in a DataSource.
+func MatchSource(input string, data DataSource, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	q, err := Compile(input, opts...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return q.MatchSource(data, opts...)
+}
+
+// MatchContext matches the input like MatchSource, but passes the context to a
+// ContextDataSource and to functions with CallContext. The evaluation is
+// aborted if the context is done, ctx.Err() is returned as *EvalError of the
+// node which was evaluated.
+func MatchContext(ctx context.Context, input string, data DataSource, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	q, err := Compile(input, opts...)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return q.MatchContext(ctx, data, opts...)
+}
+
+// MatchAny matches the input like Match, but compares the native types of the
+// values, e.g. decoded from JSON. See ValueOf for the conversion.
+func MatchAny(input string, data map[string]any, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	return MatchSource(input, AnyMap(data), opts...)
+}
+
+// Evaluate the input against the data like Match, but returns the result in
+// three-valued logic. The result is only UNKNOWN with the WithUnknown option.
+func Evaluate(input string, data map[string]string, opts ...Option) (result Truth, explanation *Explanation, err error) {
+	return EvaluateSource(input, StringMap(data), opts...)
+}
+
+// EvaluateSource evaluates the input like Evaluate, but looks up the keys in a DataSource.
+func EvaluateSource(input string, data DataSource, opts ...Option) (result Truth, explanation *Explanation, err error) {
+	q, err := Compile(input, opts...)
+	if err != nil {
+		return False, nil, err
+	}
+
+	return q.EvaluateSource(data, opts...)
+}
 
-	return query(lexer, data)
+// Match the compiled query to the data. The results are the same as from the package level Match.
+// An UNKNOWN result does not match.
+func (q *Query) Match(data map[string]string, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	return q.MatchSource(StringMap(data), opts...)
 }
 
-func query(lexer QueryLexer, data map[string]string) (ok bool, details []bool, err error) {
-	key := ""
-	operator := Token(ILLEGAL)
-	value := ""
-	isPositive := true
+// MatchAny matches the compiled query to the native values of the data.
+func (q *Query) MatchAny(data map[string]any, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	return q.MatchSource(AnyMap(data), opts...)
+}
 
-	lastWaiting := false
-	lastResult := true
+// MatchSource matches the compiled query to the keys of the DataSource.
+func (q *Query) MatchSource(data DataSource, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	result, details, err := q.EvaluateSource(data, opts...)
+	return result == True, details, err
+}
 
-	bracket := false
-	bracketResult := true
+// MatchContext matches the compiled query to the keys of the DataSource with
+// the context, see the package level MatchContext.
+func (q *Query) MatchContext(ctx context.Context, data DataSource, opts ...Option) (ok bool, explanation *Explanation, err error) {
+	result, explanation, err := q.EvaluateContext(ctx, data, opts...)
+	return result == True, explanation, err
+}
 
-	ok = true
+// Evaluate the compiled query to the data in three-valued logic. Errors on the
+// data, e.g. a division by zero, are returned as *EvalError.
+func (q *Query) Evaluate(data map[string]string, opts ...Option) (result Truth, explanation *Explanation, err error) {
+	return q.EvaluateSource(StringMap(data), opts...)
+}
 
-	for {
-		pos, tok, lit := lexer.Lex()
+// EvaluateSource evaluates the compiled query to the keys of the DataSource.
+// With an error the result is false, the explanation contains the error at
+// the failing node.
+func (q *Query) EvaluateSource(data DataSource, opts ...Option) (result Truth, explanation *Explanation, err error) {
+	return q.EvaluateContext(context.Background(), data, opts...)
+}
 
-		if tok == ILLEGAL {
-			return false, nil, fmt.Errorf("illegal query party %s on %d: %s", tok.String(), pos, lit)
+// EvaluateContext evaluates the compiled query to the keys of the DataSource
+// with the context, see MatchContext.
+func (q *Query) EvaluateContext(ctx context.Context, data DataSource, opts ...Option) (result Truth, explanation *Explanation, err error) {
+	e := &evaluator{ctx: ctx, source: q.source, data: data, options: newOptions(opts), err: &err, budget: &budget{}}
+	if source, ok := data.(ContextDataSource); ok {
+		e.lookups = &contextSource{ctx: ctx, data: source}
+		e.data = e.lookups
+	}
+
+	explanation = e.eval(q.root)
+	if err != nil {
+		return False, explanation, err
+	}
+
+	return explanation.Result, explanation, nil
+}
+
+// evaluator walks the query tree and explains the result of each node.
+// The evaluation continues after an error, the first error is returned.
+type evaluator struct {
+	ctx     context.Context
+	source  string
+	data    DataSource
+	options *options
+	err     *error
+	// lookups passes the context to a ContextDataSource
+	lookups *contextSource
+	budget  *budget
+	// macro is the name of the macro of the source
+	macro string
+	// wildcard is the wildcard key which is bound to the matched key
+	wildcard string
+	matched  string
+}
+
+// explain creates the explanation of a node.
+func (e *evaluator) explain(n node, t NodeType) *Explanation {
+	s := n.span()
+	return &Explanation{Type: t, Span: s, Text: e.source[s.Start:s.End]}
+}
+
+// fail adds the error to the explanation of the failing node.
+func (e *evaluator) fail(x *Explanation, n node, err error) *Explanation {
+	evalErr := newEvalError(e.source, n, err)
+	evalErr.Macro = e.macro
+	x.Err = evalErr
+	x.Result, x.Found = Unknown, false
+	if e.err != nil && *e.err == nil {
+		*e.err = x.Err
+	}
+	return x
+}
+
+// lookup looks up the value of a key for the explanation of a node. An error of
+// a ContextDataSource fails the node.
+func (e *evaluator) lookup(x *Explanation, n node, key string) *Explanation {
+	x.Value, x.Found = lookup(e.data, key)
+	if e.lookups != nil && e.lookups.err != nil {
+		err := e.lookups.err
+		e.lookups.err = nil
+		return e.fail(x, n, err)
+	}
+	if err := e.options.limits.checkList(x.Value); err != nil {
+		return e.fail(x, n, err)
+	}
+	return x
+}
+
+// budget is shared by the evaluators of a match. The evaluation is aborted if
+// the context is done or the step limit is exceeded.
+type budget struct {
+	steps   int
+	aborted bool
+}
+
+// step counts an evaluated node. It returns the error which aborts the
+// evaluation, only for the first aborted node.
+func (e *evaluator) step() (abort bool, err error) {
+	if e.budget.aborted {
+		return true, nil
+	}
+
+	e.budget.steps++
+	err = e.ctx.Err()
+	if max := e.options.limits.MaxSteps; err == nil && max > 0 && e.budget.steps > max {
+		err = fmt.Errorf("%w: the limit is %d", ErrTooManySteps, max)
+	}
+	e.budget.aborted = err != nil
+	return e.budget.aborted, err
+}
+
+// abort explains a node which is not evaluated because the evaluation is
+// aborted. The first of these nodes fails with the error.
+func (e *evaluator) abort(n node, condition bool, err error) *Explanation {
+	x := e.skip(n, condition)
+	if err != nil {
+		e.fail(x, n, err)
+	}
+	return x
+}
+
+// eval resolves a condition.
+func (e *evaluator) eval(n node) *Explanation {
+	if abort, err := e.step(); abort {
+		return e.abort(n, true, err)
+	}
+
+	if _, ok := n.(*groupNode); !ok {
+		if keys := wildcardKeys(n); len(keys) > 0 && keys[0].name != e.wildcard {
+			return e.anyKey(n, keys[0].name)
 		}
+	}
 
-		// stop token or next pair without right part
-		if isStopToken(tok) || (key != "" && operator == ILLEGAL && tok == IDENT) || bracket {
-			var result bool
-			if bracket {
-				result = bracketResult
-				bracket = false
-				bracketResult = true
-			} else {
-				// process current pair
-				result, err = processPair(isPositive, key, operator, value, data)
-				if err != nil {
-					return false, details, err
-				}
-			}
+	var x *Explanation
+	switch n := n.(type) {
+	case *binaryNode:
+		if n.op != AND && n.op != OR {
+			return e.compare(n)
+		}
 
-			details = append(details, result)
-
-			if tok != OR && lastWaiting {
-				ok = (result || lastResult)
-				lastWaiting = false
-				lastResult = true
-			} else if tok == OR && lastWaiting {
-				lastWaiting = true
-				lastResult = (result || lastResult)
-			} else if tok == OR {
-				lastWaiting = true
-				lastResult = result
-			} else if ok && !result {
-				ok = result
-			}
+		// the right side is skipped if the left side decides the result
+		left := e.eval(n.left)
+		var right *Explanation
+		if !e.options.full && (n.op == AND && left.Result == False || n.op == OR && left.Result == True) {
+			right = e.skip(n.right, true)
+		} else {
+			right = e.eval(n.right)
+		}
 
-			key = ""
-			operator = Token(ILLEGAL)
-			value = ""
-			isPositive = true
+		x = e.explain(n, NodeAnd)
+		x.Result = left.Result.and(right.Result)
+		if n.op == OR {
+			x.Type = NodeOr
+			x.Result = left.Result.or(right.Result)
+		}
+		x.Operator = n.op.String()
+		x.Children = []*Explanation{left, right}
+	case *notNode:
+		child := e.eval(n.x)
+
+		x = e.explain(n, NodeNot)
+		x.Operator = tokens[NOT]
+		x.Result = child.Result.not()
+		x.Children = []*Explanation{child}
+	case *groupNode:
+		child := e.eval(n.x)
+
+		x = e.explain(n, NodeGroup)
+		x.Result = child.Result
+		x.Children = []*Explanation{child}
+	case *quantifierNode:
+		return e.quantify(n)
+	case *isNode:
+		x = e.explain(n, NodePredicate)
+		x.Operator = predicateText(n)
+		x.Key = e.keyName(n.key)
+		if e.lookup(x, n.key, n.key.name).Err == nil {
+			x.Result = truthOf(predicate(x.Value, x.Found, n.negate, n.predicate))
+		}
+	case *keyNode:
+		x = e.explain(n, NodeKey)
+		x.Key = e.keyName(n)
+		if e.lookup(x, n, n.name).Err == nil {
+			x.Result = truthOf(x.Found && (!e.options.truthiness || x.Value.Truthy()))
+		}
+	case *literalNode:
+		x = e.explain(n, NodeLiteral)
+		x.Value, x.Found = n.value, true
+		x.Result = truthOf(n.value.Truthy())
+	case *paramNode:
+		x = e.value(n)
+	case *macroNode:
+		return e.expand(n, true)
+	case *callNode:
+		x = e.value(n)
+		x.Result = Unknown
+		if firstError(x) == nil {
+			x.Result = e.found(x.Found, x.Value.Truthy())
+		}
+	default:
+		x = e.fail(e.explain(n, NodeLiteral), n, errors.New("no condition"))
+	}
 
-			if tok == EOF {
-				break
-			}
+	x.Condition = true
+	return x
+}
 
-			if tok == BRACKET_RIGHT {
-				return ok, details, nil
+// skip explains a node which is not evaluated. It mirrors the query with the
+// keys and operators, but without values and with the result UNKNOWN.
+func (e *evaluator) skip(n node, condition bool) *Explanation {
+	var x *Explanation
+	switch n := n.(type) {
+	case *binaryNode:
+		switch {
+		case n.op == AND || n.op == OR:
+			x = e.explain(n, NodeAnd)
+			if n.op == OR {
+				x.Type = NodeOr
 			}
-
-			continue
-		}
-
-		// left
-		if key == "" {
-			switch tok {
-			case BRACKET_LEFT:
-				subOk, subDetails, subErr := query(lexer, data)
-				if subErr != nil {
-					return false, nil, subErr
-				}
-				details = append(details, subDetails...)
-				bracket = true
-				bracketResult = subOk
-			case N:
-				isPositive = false
-			case IDENT:
-				key = lit
-			default:
-				return false, nil, fmt.Errorf("illegal query party %s on %d: %s", tok.String(), pos, lit)
+			x.Operator = n.op.String()
+			x.Children = []*Explanation{e.skip(n.left, true), e.skip(n.right, true)}
+		case isArithmetic(n.op):
+			x = e.explain(n, NodeCalc)
+			x.Operator = n.op.String()
+			x.Children = []*Explanation{e.skip(n.left, false), e.skip(n.right, false)}
+		default:
+			x = e.explain(n, NodeCompare)
+			x.Operator = n.op.String()
+			if n.operator != nil {
+				x.Operator = n.symbol
 			}
+			x.Children = []*Explanation{e.skip(n.left, false), e.skip(n.right, false)}
+		}
+	case *notNode:
+		x = e.explain(n, NodeNot)
+		x.Operator = tokens[NOT]
+		x.Children = []*Explanation{e.skip(n.x, true)}
+	case *groupNode:
+		x = e.explain(n, NodeGroup)
+		x.Children = []*Explanation{e.skip(n.x, condition)}
+	case *quantifierNode:
+		x = e.explain(n, NodeQuantifier)
+		x.Operator = n.quantifier.String()
+		x.Key = n.key.name
+	case *isNode:
+		x = e.explain(n, NodePredicate)
+		x.Operator = predicateText(n)
+		x.Key = n.key.name
+	case *keyNode:
+		x = e.explain(n, NodeKey)
+		x.Key = n.name
+	case *literalNode:
+		x = e.explain(n, NodeLiteral)
+		x.Value = n.value
+	case *negNode:
+		x = e.explain(n, NodeCalc)
+		x.Operator = tokens[MINUS]
+		x.Children = []*Explanation{e.skip(n.x, false)}
+	case *callNode:
+		x = e.explain(n, NodeCall)
+		x.Operator = n.name
+		for _, arg := range n.args {
+			x.Children = append(x.Children, e.skip(arg, false))
+		}
+	case *macroNode:
+		x = e.explain(n, NodeMacro)
+		x.Key = n.macro.name
+	default:
+		x = e.explain(n, NodeLiteral)
+	}
 
-			continue
+	// a condition with a wildcard key is explained like its matching keys
+	if _, ok := n.(*groupNode); !ok && condition {
+		if keys := wildcardKeys(n); len(keys) > 0 && keys[0].name != e.wildcard {
+			x = &Explanation{Type: NodeWildcard, Span: x.Span, Text: x.Text, Key: keys[0].name}
 		}
+	}
 
-		// operator
-		if operator == ILLEGAL {
-			if isOperator(tok) {
-				operator = tok
-			} else {
-				return false, nil, fmt.Errorf("illegal query party %s on %d: %s", tok.String(), pos, lit)
-			}
+	x.Condition, x.Skipped, x.Result = condition, true, Unknown
+	return x
+}
+
+// expand evaluates a macro as condition or value. The explanation of the macro
+// has the explanation of its source as child.
+func (e *evaluator) expand(n *macroNode, condition bool) *Explanation {
+	scope := *e
+	scope.source, scope.macro, scope.wildcard, scope.matched = n.macro.source, n.macro.name, "", ""
+
+	var child *Explanation
+	if condition {
+		child = scope.eval(n.macro.root)
+	} else {
+		child = scope.value(n.macro.root)
+	}
 
-			continue
+	x := e.explain(n, NodeMacro)
+	x.Key = n.macro.name
+	x.Value, x.Found = child.Value, available(child)
+	x.Condition, x.Result = child.Condition, child.Result
+	x.Children = []*Explanation{child}
+	return x
+}
+
+// quantify evaluates the condition of a quantifier with each item of the list
+// as data. Keys of items which are no objects are missing. A missing key or a
+// value which is no list is false, or UNKNOWN with the WithUnknown option. The
+// items of a wildcard key are the values of the matching keys. A wildcard
+// quantifier evaluates the condition with each matching key instead.
+func (e *evaluator) quantify(n *quantifierNode) *Explanation {
+	x := e.explain(n, NodeQuantifier)
+	x.Operator = n.quantifier.String()
+	x.Key = n.key.name
+	x.Condition = true
+
+	var scopes []*evaluator
+	var items []*Explanation
+	addItem := func(scope *evaluator, key string, value Value) {
+		item := e.explain(n.x, NodeItem)
+		item.Key, item.Value, item.Found, item.Condition = key, value, true, true
+		scopes = append(scopes, scope)
+		items = append(items, item)
+	}
+
+	switch {
+	case n.wildcard:
+		for _, m := range scan(e.data, n.key.name) {
+			addItem(e.bind(n.key.name, m), m.key, m.value)
+		}
+	case isWildcard(n.key.name):
+		for _, m := range scan(e.data, n.key.name) {
+			addItem(e.scope(m.value), m.key, m.value)
 		}
+	default:
+		if e.lookup(x, n.key, n.key.name).Err != nil {
+			return x
+		}
+		if !x.Found || x.Value.Kind() != KindList {
+			x.Result = e.found(false, false)
+			return x
+		}
+		for i, value := range x.Value.List() {
+			addItem(e.scope(value), n.key.name+"["+strconv.Itoa(i)+"]", value)
+		}
+	}
 
-		// right
-		switch tok {
-		case IDENT:
-			value = lit
-		case NUMBER:
-			value = lit
-		default:
-			return false, nil, fmt.Errorf("illegal query party %s on %d: %s", tok.String(), pos, lit)
+	x.Result = truthOf(n.quantifier == ALL)
+	for i, scope := range scopes {
+		child := scope.eval(n.x)
+		items[i].Result = child.Result
+		items[i].Children = []*Explanation{child}
+
+		if n.quantifier == ALL {
+			x.Result = x.Result.and(child.Result)
+		} else {
+			x.Result = x.Result.or(child.Result)
 		}
 	}
 
-	return ok, details, nil
+	if n.quantifier == NONE {
+		x.Result = x.Result.not()
+	}
+
+	x.Children = items
+	return x
+}
+
+// scope returns an evaluator with the fields of an item as data.
+func (e *evaluator) scope(item Value) *evaluator {
+	fields, ok := item.Object()
+	if !ok {
+		fields = StringMap(nil)
+	}
+	scope := *e
+	scope.data, scope.wildcard, scope.matched = fields, "", ""
+	return &scope
+}
+
+// bind returns an evaluator for the data with the wildcard key bound to a matching key.
+func (e *evaluator) bind(wildcard string, m match) *evaluator {
+	bound := *e
+	bound.data = wildcardScope{DataSource: e.data, pattern: wildcard, value: m.value, found: m.key != ""}
+	bound.wildcard, bound.matched = wildcard, m.key
+	return &bound
 }
 
-func processPair(isPositive bool, key string, operator Token, value string, data map[string]string) (bool, error) {
-	dataValue, keyFound := data[key]
+// keyName returns the name of the key, for the bound wildcard key the matched key.
+func (e *evaluator) keyName(n *keyNode) string {
+	if n.name == e.wildcard && e.matched != "" {
+		return e.matched
+	}
+	return n.name
+}
 
-	// only key
-	if operator == ILLEGAL {
-		return keyFound == isPositive, nil
+// anyKey resolves a condition with a wildcard key, which is true if it is true
+// for any matching key. Without a matching key the condition is resolved like
+// for a missing key.
+func (e *evaluator) anyKey(n node, wildcard string) *Explanation {
+	matches := scan(e.data, wildcard)
+	if len(matches) == 0 {
+		matches = append(matches, match{})
 	}
 
-	// key not found
-	if !keyFound {
-		return keyFound == isPositive, nil
+	x := e.explain(n, NodeWildcard)
+	x.Key = wildcard
+	x.Condition = true
+	x.Result = False
+	for _, m := range matches {
+		child := e.bind(wildcard, m).eval(n)
+		x.Result = x.Result.or(child.Result)
+		x.Children = append(x.Children, child)
 	}
 
-	// operator
-	result := false
-	switch operator {
-	case EQ:
-		result = value == dataValue
-	case GT:
-		expectedFloat, err := strconv.ParseFloat(value, 32)
-		if err != nil {
-			return false, err
+	return x
+}
+
+// compare resolves a comparison. A missing key is only equal to NULL, any other
+// comparison with it is false, or UNKNOWN with the WithUnknown option. The
+// result is also the bool value of the comparison.
+func (e *evaluator) compare(n *binaryNode) *Explanation {
+	left := e.value(n.left)
+	right := e.value(n.right)
+
+	x := e.explain(n, NodeCompare)
+	x.Operator = n.op.String()
+	if n.operator != nil {
+		x.Operator = n.symbol
+	}
+	x.Condition = true
+	x.Children = []*Explanation{left, right}
+
+	if firstError(left) != nil || firstError(right) != nil {
+		x.Result = Unknown
+		return x
+	}
+
+	l, r := left.Value, right.Value
+	if !available(left) || !available(right) {
+		if !isNullLiteral(n.left) && !isNullLiteral(n.right) {
+			x.Result = e.found(false, false)
+			x.Value, x.Found = BoolValue(false), x.Result != Unknown
+			return x
 		}
-		actualFloat, err := strconv.ParseFloat(dataValue, 32)
-		if err != nil {
-			return false, err
+
+		if !available(left) {
+			l = NullValue()
 		}
+		if !available(right) {
+			r = NullValue()
+		}
+	}
 
-		result = actualFloat > expectedFloat
-	case GTE:
-		expectedFloat, err := strconv.ParseFloat(value, 32)
-		if err != nil {
-			return false, err
+	var result bool
+	var err error
+	if n.operator != nil {
+		result, err = n.operator.Compare(l, r)
+	} else {
+		if n.op == EQ || n.op == NE {
+			l, r = literalText(n.left, l, r), literalText(n.right, r, l)
 		}
-		actualFloat, err := strconv.ParseFloat(dataValue, 32)
-		if err != nil {
-			return false, err
+		result, err = compare(n.op, l, r)
+	}
+	if err != nil {
+		return e.fail(x, n, err)
+	}
+
+	x.Result = truthOf(result)
+	x.Value, x.Found = BoolValue(result), true
+	return x
+}
+
+// literalText returns a number or null literal as written if it is compared
+// with a text, e.g. zip=01234 does not match 1234, a=10 does not match 10.0
+// and status=null matches the text null.
+func literalText(n node, v Value, other Value) Value {
+	if _, ok := n.(*literalNode); ok && (v.Kind() == KindNumber || v.Kind() == KindNull) && other.Kind() == KindString {
+		return StringValue(v.String())
+	}
+	return v
+}
+
+// found returns the result of a condition, which is false or UNKNOWN if a key is missing.
+func (e *evaluator) found(found bool, result bool) Truth {
+	if !found && e.options.unknown {
+		return Unknown
+	}
+
+	return truthOf(found && result)
+}
+
+// available reports whether an operand has a value. A key with a null value
+// can only be compared with NULL like a missing key.
+func available(x *Explanation) bool {
+	return x.Found && (x.Type != NodeKey || x.Value.Kind() != KindNull)
+}
+
+// value calculates the value of an operand, it is not found if a key is
+// missing. An operand of a failed operand has no value and no error.
+func (e *evaluator) value(n node) *Explanation {
+	if abort, err := e.step(); abort {
+		return e.abort(n, false, err)
+	}
+
+	switch n := n.(type) {
+	case *keyNode:
+		x := e.explain(n, NodeKey)
+		x.Key = e.keyName(n)
+		return e.lookup(x, n, n.name)
+	case *literalNode:
+		x := e.explain(n, NodeLiteral)
+		x.Value, x.Found = n.value, true
+		return x
+	case *paramNode:
+		return e.fail(e.explain(n, NodeLiteral), n, fmt.Errorf("%w: %s", ErrUnboundParam, n.paramName()))
+	case *macroNode:
+		return e.expand(n, false)
+	case *groupNode:
+		child := e.value(n.x)
+
+		x := e.explain(n, NodeGroup)
+		x.Value, x.Found = child.Value, available(child)
+		x.Condition, x.Result = child.Condition, child.Result
+		x.Children = []*Explanation{child}
+		return x
+	case *negNode:
+		child := e.value(n.x)
+
+		x := e.explain(n, NodeCalc)
+		x.Operator = tokens[MINUS]
+		x.Children = []*Explanation{child}
+		if !available(child) {
+			return x
 		}
 
-		result = actualFloat >= expectedFloat
-	case LT:
-		expectedFloat, err := strconv.ParseFloat(value, 32)
+		number, err := child.Value.Number()
 		if err != nil {
-			return false, err
+			return e.fail(x, n, err)
 		}
-		actualFloat, err := strconv.ParseFloat(dataValue, 32)
-		if err != nil {
-			return false, err
+		x.Value, x.Found = NumberValue(-number), true
+		return x
+	case *binaryNode:
+		if !isArithmetic(n.op) {
+			return e.compare(n)
 		}
 
-		result = actualFloat < expectedFloat
-	case LTE:
-		expectedFloat, err := strconv.ParseFloat(value, 32)
+		left := e.value(n.left)
+		right := e.value(n.right)
+
+		x := e.explain(n, NodeCalc)
+		x.Operator = n.op.String()
+		x.Children = []*Explanation{left, right}
+		if !available(left) || !available(right) {
+			return x
+		}
+
+		value, err := calculate(n.op, left.Value, right.Value)
 		if err != nil {
-			return false, err
+			return e.fail(x, n, err)
+		}
+		x.Value, x.Found = value, true
+		return x
+	case *callNode:
+		x := e.explain(n, NodeCall)
+		x.Operator = n.name
+
+		args := make([]Value, len(n.args))
+		for i, arg := range n.args {
+			child := e.value(arg)
+			x.Children = append(x.Children, child)
+			if !available(child) {
+				return x
+			}
+
+			var err error
+			args[i], err = convert(child.Value, n.fn.param(i))
+			if err != nil {
+				return e.fail(x, n, err)
+			}
 		}
-		actualFloat, err := strconv.ParseFloat(dataValue, 32)
+
+		result, err := n.fn.call(e.ctx, args)
 		if err != nil {
-			return false, err
+			return e.fail(x, n, err)
 		}
+		x.Value, x.Found = result, true
+		return x
+	}
+
+	return e.fail(e.explain(n, NodeLiteral), n, errors.New("no value"))
+}
 
-		result = actualFloat <= expectedFloat
-	case NE:
-		result = value != dataValue
+func isNullLiteral(n node) bool {
+	l, ok := n.(*literalNode)
+	return ok && l.value.Kind() == KindNull
+}
+
+// predicate resolves IS [NOT] EMPTY, MISSING, BLANK or NULL and EXISTS for
+// the value of a key. Apart from MISSING, NULL and EXISTS a predicate requires
+// the key to exist, so a missing key is neither empty nor not empty. A key
+// with a null value is NULL, but not MISSING.
+func predicate(value Value, keyFound bool, negate bool, predicate Token) bool {
+	isNull := !keyFound || value.Kind() == KindNull
+
+	switch predicate {
+	case MISSING:
+		return !keyFound != negate
+	case EXISTS:
+		return keyFound != negate
+	case NULL:
+		return isNull != negate
+	case EMPTY:
+		return !isNull && (value.String() == "") != negate
+	case BLANK:
+		return !isNull && (strings.TrimSpace(value.String()) == "") != negate
 	}
 
-	return result == isPositive, nil
+	return false
+}
+
+// predicateText returns the predicate as written in a query, e.g. IS NOT EMPTY.
+func predicateText(n *isNode) string {
+	switch {
+	case n.predicate == EXISTS:
+		return tokens[EXISTS]
+	case n.negate:
+		return tokens[IS] + " " + tokens[NOT] + " " + n.predicate.String()
+	}
+	return tokens[IS] + " " + n.predicate.String()
 }
 
-func isStopToken(token Token) bool {
-	return token == EOF || token == AND || token == OR || token == BRACKET_RIGHT
+// isTruthy reports whether a value counts as true. Empty values, zero and
+// the words false, no, off and null are false, everything else is true.
+func isTruthy(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", "false", "f", "no", "n", "off", "null", "nil":
+		return false
+	}
+
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number != 0
+	}
+
+	return true
 }
 
 func isOperator(token Token) bool {
@@ -1,8 +1,12 @@
 package simplequery
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,9 +14,9 @@ import (
 func TestMatch(t *testing.T) {
 	t.Parallel()
 
-	ok, details, err := Match("(existingKey AND foo) OR (abc AND def)", map[string]string{})
+	ok, explanation, err := Match("(existingKey AND foo) OR (abc AND def)", map[string]string{})
 	assert.False(t, ok)
-	assert.Len(t, details, 6)
+	assert.Len(t, explanation.Details(), 6)
 	assert.NoError(t, err)
 }
 
@@ -67,7 +71,7 @@ func TestQuery(t *testing.T) {
 			query:   "existingKey OR foo",
 			data:    map[string]string{"existingKey": "value", "foo": "abc"},
 			ok:      true,
-			details: []bool{true, true},
+			details: []bool{true, false},
 		},
 		{
 			query:   "existingKey OR foo",
@@ -109,7 +113,7 @@ func TestQuery(t *testing.T) {
 			query:   "existingKey!=value AND foo=abc",
 			data:    map[string]string{"existingKey": "value", "foo": "abc"},
 			ok:      false,
-			details: []bool{false, true},
+			details: []bool{false, false},
 		},
 		{
 			query:   "existingKey!=value AND foo=abc",
@@ -211,7 +215,7 @@ func TestQuery(t *testing.T) {
 			query:   "(existingKey OR foo)",
 			data:    map[string]string{"existingKey": "value", "foo": "abc"},
 			ok:      true,
-			details: []bool{true, true, true},
+			details: []bool{true, false, true},
 		},
 		{
 			query:   "(!existingKey OR !foo)",
@@ -225,6 +229,62 @@ func TestQuery(t *testing.T) {
 			ok:      true,
 			details: []bool{true, true, true, false, false, false},
 		},
+		{
+			query:   "existingKey OR foo AND abc",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: []bool{true, false, false},
+		},
+		{
+			query:   "existingKey foo",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: []bool{true, false},
+		},
+		{
+			query:   "!(existingKey OR foo)",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: []bool{true, false, true},
+		},
+		{
+			query:   "existingKey=true AND foo=false",
+			data:    map[string]string{"existingKey": "yes", "foo": "0"},
+			ok:      true,
+			details: []bool{true, true},
+		},
+		{
+			query:   "existingKey=true OR foo!=false",
+			data:    map[string]string{"existingKey": "off"},
+			ok:      false,
+			details: []bool{false, false},
+		},
+		{
+			query:   "existingKey=null OR foo!=null",
+			data:    map[string]string{"foo": ""},
+			ok:      true,
+			details: []bool{true, false},
+		},
+		{
+			query:   "!foo=null AND true AND !false",
+			data:    map[string]string{"foo": ""},
+			ok:      true,
+			details: []bool{true, true, true},
+		},
+		{
+			query:   "existingKey>true",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: nil,
+			error:   true,
+		},
+		{
+			query:   "(existingKey",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: nil,
+			error:   true,
+		},
 		{
 			query:   "#",
 			data:    map[string]string{"existingKey": "value"},
@@ -232,15 +292,28 @@ func TestQuery(t *testing.T) {
 			details: nil,
 			error:   true,
 		},
+		{
+			query:   "!",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: nil,
+			error:   true,
+		},
+		{
+			query:   "existingKey AND !",
+			data:    map[string]string{"existingKey": "value"},
+			ok:      false,
+			details: nil,
+			error:   true,
+		},
 	}
 
 	for i, testCase := range testCases {
-		lex := NewLexer(testCase.query)
-		ok, details, err := query(lex, testCase.data)
+		ok, explanation, err := Match(testCase.query, testCase.data)
 
 		testCase.desc = fmt.Sprintf("%d: %s (%s)", i, testCase.query, testCase.desc)
 		assert.Equal(t, testCase.ok, ok, testCase.desc)
-		assert.Equal(t, testCase.details, details, testCase.desc)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.desc)
 		if testCase.error {
 			assert.Error(t, err, testCase.desc)
 		} else {
@@ -249,7 +322,559 @@ func TestQuery(t *testing.T) {
 	}
 }
 
-func TestProcessPair(t *testing.T) {
+func TestCompile(t *testing.T) {
+	t.Parallel()
+
+	q, err := Compile("existingKey AND foo=bar")
+	assert.NoError(t, err)
+
+	ok, explanation, err := q.Match(map[string]string{"existingKey": "", "foo": "bar"})
+	assert.True(t, ok)
+	assert.Equal(t, []bool{true, true}, explanation.Details())
+	assert.NoError(t, err)
+
+	ok, explanation, err = q.Match(map[string]string{"foo": "bar"})
+	assert.False(t, ok)
+	assert.Equal(t, []bool{false, false}, explanation.Details())
+	assert.NoError(t, err)
+
+	ok, explanation, err = q.Match(map[string]string{"foo": "bar"}, WithFullEvaluation())
+	assert.False(t, ok)
+	assert.Equal(t, []bool{false, true}, explanation.Details())
+	assert.NoError(t, err)
+
+	_, err = Compile("existingKey AND")
+	assert.Error(t, err)
+}
+
+func TestMatchWithoutRightSide(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"empty": "", "name": "abc", "status": "null"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "empty=", ok: true},
+		{query: "empty!=", ok: false},
+		{query: "name!=", ok: true},
+		{query: "name= OR empty=", ok: true},
+		{query: "(name!=) AND empty=", ok: true},
+		{query: "missingKey=", ok: false},
+		{query: "status=null", ok: true},
+		{query: "status!=null", ok: false},
+		{query: "name=null OR empty=null", ok: false},
+		{query: "missingKey=null", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	for _, query := range []string{"name= =", "name=,", "name+"} {
+		_, err := Compile(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestMatchWithTruthiness(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"isVip": "yes", "isBlocked": "false", "count": "0", "emptyValue": ""}
+
+	testCases := []struct {
+		query  string
+		ok     bool
+		exists bool
+	}{
+		{query: "isVip", ok: true, exists: true},
+		{query: "isBlocked", ok: false, exists: true},
+		{query: "!isBlocked", ok: true, exists: false},
+		{query: "count", ok: false, exists: true},
+		{query: "emptyValue", ok: false, exists: true},
+		{query: "unknownKey", ok: false, exists: false},
+		{query: "!unknownKey", ok: true, exists: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data, WithTruthiness())
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+
+		// without the option a bare key only has to exist
+		ok, _, err = Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.exists, ok, testCase.query)
+	}
+}
+
+func TestEvaluateWithUnknown(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"amount": "10", "name": "abc"}
+
+	testCases := []struct {
+		query   string
+		result  Truth
+		details []bool
+	}{
+		{query: "amount>5", result: True, details: []bool{true}},
+		{query: "missingKey>5", result: Unknown, details: []bool{false}},
+		{query: "!missingKey>5", result: Unknown, details: []bool{false}},
+		{query: "NOT (missingKey>5)", result: Unknown, details: []bool{false, false}},
+		{query: "missingKey=abc AND amount>5", result: Unknown, details: []bool{false, true}},
+		{query: "missingKey=abc AND amount<5", result: False, details: []bool{false, false}},
+		{query: "missingKey=abc OR amount>5", result: True, details: []bool{false, true}},
+		{query: "missingKey=abc OR amount<5", result: Unknown, details: []bool{false, false}},
+		{query: "missingKey=true", result: Unknown, details: []bool{false}},
+		{query: "missingKey=null", result: True, details: []bool{true}},
+		{query: "missingKey", result: False, details: []bool{false}},
+		{query: "missingKey IS EMPTY", result: False, details: []bool{false}},
+	}
+
+	for _, testCase := range testCases {
+		result, explanation, err := Evaluate(testCase.query, data, WithUnknown())
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.result, result, testCase.query)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.query)
+
+		ok, _, err := Match(testCase.query, data, WithUnknown())
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.result == True, ok, testCase.query)
+	}
+
+	// without the option a comparison on a missing key is false
+	result, _, err := Evaluate("!missingKey>5", data)
+	assert.NoError(t, err)
+	assert.Equal(t, True, result)
+
+	_, _, err = Evaluate("amount AND", data)
+	assert.Error(t, err)
+}
+
+func TestShortCircuit(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		query   string
+		full    bool
+		result  Truth
+		lookups []string
+		skipped []string
+	}{
+		{query: "a AND b", result: True, lookups: []string{"a", "b"}},
+		{query: "x AND b", result: False, lookups: []string{"x"}, skipped: []string{"b"}},
+		{query: "a OR b", result: True, lookups: []string{"a"}, skipped: []string{"b"}},
+		{query: "x OR b", result: True, lookups: []string{"x", "b"}},
+		{query: "a OR (b AND c=1) OR d", result: True, lookups: []string{"a"}, skipped: []string{"(b AND c=1)", "d"}},
+		{query: "x AND prices.* > 1", result: False, lookups: []string{"x"}, skipped: []string{"prices.* > 1"}},
+		{query: "x AND ANY items: y", result: False, lookups: []string{"x"}, skipped: []string{"ANY items: y"}},
+		{query: "x AND b", full: true, result: False, lookups: []string{"x", "b"}},
+		{query: "a OR lower(b) = c", full: true, result: True, lookups: []string{"a", "b"}},
+	}
+
+	for _, testCase := range testCases {
+		lookups := []string{}
+		data := DataSourceFunc(func(key string) (Value, bool) {
+			lookups = append(lookups, key)
+			if key == "a" || key == "b" {
+				return StringValue("1"), true
+			}
+			return Value{}, false
+		})
+
+		opts := []Option{WithUnknown()}
+		if testCase.full {
+			opts = append(opts, WithFullEvaluation())
+		}
+
+		result, explanation, err := EvaluateSource(testCase.query, data, opts...)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.result, result, testCase.query)
+		assert.Equal(t, testCase.lookups, lookups, testCase.query)
+
+		skipped := []string{}
+		explanation.walk(func(x *Explanation) {
+			if x.Skipped && x.Condition {
+				skipped = append(skipped, x.Text)
+			}
+		})
+		assert.Subset(t, skipped, testCase.skipped, testCase.query)
+		if testCase.skipped == nil {
+			assert.Empty(t, skipped, testCase.query)
+		}
+	}
+}
+
+func TestMatchContext(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+
+	lookups := []string{}
+	data := ContextDataSourceFunc(func(ctx context.Context, key string) (Value, bool, error) {
+		lookups = append(lookups, key)
+		switch key {
+		case "tenant":
+			return StringValue(ctx.Value(ctxKey{}).(string)), true, nil
+		case "broken":
+			return Value{}, false, errors.New("store unavailable")
+		case "cancel":
+			ctx.Value(ctxKey{}).(context.CancelFunc)()
+		}
+		return StringValue("1"), true, nil
+	})
+
+	registry := NewFunctionRegistry()
+	assert.NoError(t, registry.Register("region", Function{
+		Result: KindString,
+		CallContext: func(ctx context.Context, args []Value) (Value, error) {
+			return StringValue(ctx.Value(ctxKey{}).(string) + "-eu"), nil
+		},
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "acme")
+	ok, _, err := MatchContext(ctx, "tenant = acme AND region() = 'acme-eu'", data, WithFunctions(registry))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = MatchContext(ctx, "ns:tenant = acme", Namespaces{"ns": data})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// an error of the data source fails the key
+	ok, explanation, err := MatchContext(ctx, "a OR broken > 1", data, WithFullEvaluation())
+	assert.False(t, ok)
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "broken", evalErr.Text)
+		assert.EqualError(t, evalErr.Err, "store unavailable")
+	}
+	assert.Equal(t, Unknown, explanation.Children[1].Result)
+
+	// the evaluation is aborted at the node after the cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	lookups = lookups[:0]
+	ok, explanation, err = MatchContext(context.WithValue(ctx, ctxKey{}, cancel), "a AND cancel AND (b OR c)", data)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, context.Canceled)
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "(b OR c)", evalErr.Text)
+	}
+	assert.Equal(t, []string{"a", "cancel"}, lookups)
+	assert.True(t, explanation.Children[1].Skipped)
+
+	ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	q, err := Compile("a AND b")
+	assert.NoError(t, err)
+	_, _, err = q.MatchContext(ctx, data)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "a AND b", evalErr.Text)
+	}
+}
+
+func TestMatchArithmetic(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"net": "80", "tax": "15.2", "quantity": "4", "price": "250", "zero": "0", "name": "abc"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "net + tax > 95", ok: true},
+		{query: "net + tax > 100", ok: false},
+		{query: "quantity * price >= 1000", ok: true},
+		{query: "net - tax * 2 = 49.6", ok: true},
+		{query: "(net - tax) * 2 = 129.6", ok: true},
+		{query: "net / quantity = 20 AND net % 3 = 2", ok: true},
+		{query: "-net < 0 AND net > -1", ok: true},
+		{query: "--net = 80", ok: true},
+		{query: "net = 40 + 40", ok: true},
+		{query: "net + 1 = 81.0", ok: true},
+		{query: "net + missingKey > 0", ok: false},
+		{query: "!net + missingKey > 0", ok: true},
+		{query: "net + missingKey = null", ok: true},
+		{query: "(net + tax) > 95 OR name", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	result, _, err := Evaluate("net + missingKey > 0", data, WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+}
+
+// findExplanation returns the first explained node with the span.
+func findExplanation(x *Explanation, span Span) *Explanation {
+	if x.Span == span {
+		return x
+	}
+	for _, child := range x.Children {
+		if found := findExplanation(child, span); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestMatchNumberText(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"zip": "01234", "a": "10.0", "b": "10"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "zip=01234", ok: true},
+		{query: "zip=1234", ok: false},
+		{query: "zip!=1234", ok: true},
+		{query: "a=10", ok: false},
+		{query: "a=10.0 AND b=10", ok: true},
+		{query: "a>=10 AND a<=10", ok: true},
+		{query: "a=b + 0", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+
+	ok, _, err := MatchAny("a=10", map[string]any{"a": 10.0})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestMatchArithmeticErrors(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"net": "80", "zero": "0", "name": "abc", "a": "1"}
+
+	for _, query := range []string{"net +", "net + > 1", "net + 1", "name AND -net", "(a AND b) + 1 > 2", "net * true > 1", "net + 1 IS EMPTY", "5"} {
+		_, _, err := Match(query, data)
+		assert.Error(t, err, query)
+
+		var evalErr *EvalError
+		assert.False(t, errors.As(err, &evalErr), query)
+	}
+
+	testCases := []struct {
+		query string
+		text  string
+		span  Span
+		err   error
+	}{
+		{query: "net / zero > 1", text: "net / zero", span: Span{0, 10}, err: ErrDivisionByZero},
+		{query: "a AND net % (zero * 2) > 1", text: "net % (zero * 2)", span: Span{6, 22}, err: ErrDivisionByZero},
+		{query: "name + 1 > 1", text: "name + 1", span: Span{0, 8}},
+		{query: "-name > 1", text: "-name", span: Span{0, 5}},
+		{query: "net > name", text: "net > name", span: Span{0, 10}},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := Match(testCase.query, data)
+		assert.False(t, ok, testCase.query)
+		assert.Equal(t, err, findExplanation(explanation, testCase.span).Err, testCase.query)
+
+		var evalErr *EvalError
+		if assert.True(t, errors.As(err, &evalErr), testCase.query) {
+			assert.Equal(t, testCase.text, evalErr.Text, testCase.query)
+			assert.Equal(t, testCase.span, evalErr.Span, testCase.query)
+			assert.Contains(t, evalErr.Error(), testCase.text, testCase.query)
+		}
+		if testCase.err != nil {
+			assert.ErrorIs(t, err, testCase.err, testCase.query)
+		}
+	}
+}
+
+func TestMatchQuantifiers(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{}
+	err := json.Unmarshal([]byte(`{
+		"items": [{"sku": "X1", "qty": 3}, {"sku": "X2", "qty": 1}],
+		"approvals": [{"state": "approved"}, {"state": "approved"}],
+		"nothing": [],
+		"tags": ["a", "b"],
+		"name": "order"
+	}`), &data)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		query   string
+		ok      bool
+		details []bool
+	}{
+		{query: `ANY items: (sku="X1" AND qty>2)`, ok: true, details: []bool{true, false, true}},
+		{query: `ANY items: (sku="X2" AND qty>2)`, ok: false, details: []bool{false, false, false}},
+		{query: "ALL approvals: state=approved", ok: true, details: []bool{true, true, true}},
+		{query: "ALL items: qty>2", ok: false, details: []bool{true, false, false}},
+		{query: "NONE items: qty>5", ok: true, details: []bool{false, false, true}},
+		{query: "none items: qty>2", ok: false, details: []bool{true, false, false}},
+		{query: "!ANY items: qty>5", ok: true, details: []bool{false, false, false}},
+		{query: "ANY items: !qty>2", ok: true, details: []bool{false, true, true}},
+		{query: "ANY items: qty>2 AND name", ok: true, details: []bool{true, false, true, true}},
+		{query: "name ALL approvals: state=approved", ok: true, details: []bool{true, true, true, true}},
+		{query: "ANY items: name", ok: false, details: []bool{false, false, false}},
+		{query: "ANY nothing: sku", ok: false, details: []bool{false}},
+		{query: "ALL nothing: sku", ok: true, details: []bool{true}},
+		{query: "NONE nothing: sku", ok: true, details: []bool{true}},
+		{query: "ANY unknownKey: sku", ok: false, details: []bool{false}},
+		{query: "ALL name: sku", ok: false, details: []bool{false}},
+		{query: "ANY tags: sku", ok: false, details: []bool{false, false, false}},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := MatchAny(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.query)
+	}
+
+	result, _, err := EvaluateSource("ALL unknownKey: sku", AnyMap(data), WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+
+	result, _, err = EvaluateSource("ALL items: unknownKey > 1", AnyMap(data), WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+
+	_, _, err = MatchAny("ANY items: qty / 0 > 1", data)
+	var evalErr *EvalError
+	if assert.True(t, errors.As(err, &evalErr)) {
+		assert.Equal(t, "qty / 0", evalErr.Text)
+	}
+
+	for _, query := range []string{"ANY items", "ANY items (qty>1)", "ANY : qty>1", "ANY 1: qty>1", "ANY items: qty + 1", "ANY items:", "(ANY items: qty>1) = true"} {
+		_, err := Compile(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestMatchQuantifierWords(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"status": "none", "mode": "all", "any": "1", "none": "x"}
+
+	testCases := []struct {
+		query   string
+		ok      bool
+		details []bool
+	}{
+		{query: "status=none", ok: true, details: []bool{true}},
+		{query: "status!=exists AND EXISTS any AND !exists", ok: true, details: []bool{true, true, true}},
+		{query: "mode=all AND status!=any", ok: true, details: []bool{true, true}},
+		{query: "any=1 AND none", ok: true, details: []bool{true, true}},
+		{query: "all OR !none", ok: false, details: []bool{false, false}},
+		{query: "any IS NOT EMPTY AND all IS MISSING", ok: true, details: []bool{true, true}},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.query)
+	}
+}
+
+func TestMatchPredicates(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"emptyKey": "", "blankKey": " \t", "filled": "abc"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "emptyKey IS EMPTY", ok: true},
+		{query: "blankKey IS EMPTY", ok: false},
+		{query: "missingKey IS EMPTY", ok: false},
+		{query: "emptyKey IS NOT EMPTY", ok: false},
+		{query: "filled IS NOT EMPTY", ok: true},
+		{query: "missingKey IS NOT EMPTY", ok: false},
+		{query: "emptyKey IS BLANK", ok: true},
+		{query: "blankKey IS BLANK", ok: true},
+		{query: "filled IS BLANK", ok: false},
+		{query: "missingKey IS BLANK", ok: false},
+		{query: "blankKey IS NOT BLANK", ok: false},
+		{query: "filled IS NOT BLANK", ok: true},
+		{query: "missingKey IS MISSING", ok: true},
+		{query: "emptyKey IS MISSING", ok: false},
+		{query: "emptyKey IS NOT MISSING", ok: true},
+		{query: "missingKey IS NULL", ok: true},
+		{query: "filled is not null", ok: true},
+		{query: "!filled IS EMPTY", ok: true},
+		{query: "NOT missingKey IS NOT EMPTY", ok: true},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+		assert.Equal(t, []bool{testCase.ok}, explanation.Details(), testCase.query)
+	}
+
+	for _, query := range []string{"filled IS", "filled IS NOT", "filled IS abc", "filled IS NOT NOT EMPTY"} {
+		_, _, err := Match(query, data)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestMatchPredicateWords(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"status": "empty", "reason": "missing", "empty": "", "is": "x", "not": "1", "blank": "no"}
+
+	testCases := []struct {
+		query   string
+		ok      bool
+		details []bool
+	}{
+		{query: "status=empty", ok: true, details: []bool{true}},
+		{query: "status!=empty", ok: false, details: []bool{false}},
+		{query: "reason=missing", ok: true, details: []bool{true}},
+		{query: "status=blank OR status=is OR status=not", ok: false, details: []bool{false, false, false}},
+		{query: "empty", ok: true, details: []bool{true}},
+		{query: "empty IS EMPTY AND is=x", ok: true, details: []bool{true, true}},
+		{query: "not=1 AND blank", ok: true, details: []bool{true, true}},
+		{query: "not AND !missing", ok: true, details: []bool{true, true}},
+		{query: "NOT missing", ok: true, details: []bool{true}},
+		{query: "status=empty NOT blank=no", ok: false, details: []bool{true, false}},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := Match(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.query)
+	}
+}
+
+func TestIsTruthy(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{"true", "1", "yes", "Y", "on", "abc", "-2", "0.5"} {
+		assert.True(t, isTruthy(value), value)
+	}
+
+	for _, value := range []string{"", " ", "false", "FALSE", "0", "0.0", "no", "off", "null"} {
+		assert.False(t, isTruthy(value), value)
+	}
+}
+
+func TestCondition(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
@@ -485,7 +1110,19 @@ func TestProcessPair(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		ok, err := processPair(testCase.isPositive, testCase.key, testCase.operator, testCase.value, testCase.data)
+		var n node = &keyNode{name: testCase.key}
+		if testCase.operator != ILLEGAL {
+			n = &binaryNode{op: testCase.operator, left: n, right: &literalNode{value: numberLiteral(testCase.value)}}
+		}
+
+		if !testCase.isPositive {
+			n = &notNode{x: n}
+		}
+
+		var err error
+		e := &evaluator{ctx: context.Background(), data: StringMap(testCase.data), options: newOptions(nil), err: &err, budget: &budget{}}
+		x := e.eval(n)
+		ok := x.Result == True
 		assert.Equal(t, testCase.ok, ok, testCase.desc)
 		if testCase.isError {
 			assert.Error(t, err, testCase.desc)
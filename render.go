@@ -0,0 +1,202 @@
+package simplequery
+
+import (
+	"errors"
+	"strings"
+)
+
+// RenderStyle is the output format of a rendered explanation.
+type RenderStyle int
+
+const (
+	RenderPlain    RenderStyle = iota // indented plain text
+	RenderTerminal                    // indented text with ANSI colours
+	RenderMarkdown                    // nested Markdown list
+)
+
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+)
+
+var truthColors = []string{
+	False:   colorRed,
+	True:    colorGreen,
+	Unknown: colorYellow,
+}
+
+// String renders the explanation as plain text.
+func (x *Explanation) String() string {
+	return x.Render(RenderPlain)
+}
+
+// Render renders the explanation with one line per condition, e.g.
+// "amount>1000 was false because amount=750". The operands of a condition are
+// listed with their values, missing keys and errors. The conditions of AND, OR,
+// NOT, quantifiers and wildcard keys are indented below them, a skipped
+// condition is "not evaluated".
+func (x *Explanation) Render(style RenderStyle) string {
+	if x == nil {
+		return ""
+	}
+
+	r := &renderer{style: style}
+	r.render(x, 0)
+	return r.String()
+}
+
+type renderer struct {
+	strings.Builder
+	style RenderStyle
+}
+
+func (r *renderer) render(x *Explanation, depth int) {
+	if x.Skipped {
+		r.line(depth, r.code(x.Text)+" was not evaluated")
+		return
+	}
+
+	switch x.Type {
+	case NodeAnd, NodeOr:
+		r.line(depth, r.code(x.Text)+" was "+r.result(x.Result))
+		for _, child := range r.chain(x, nil) {
+			r.render(child, depth+1)
+		}
+	case NodeGroup:
+		r.render(x.Children[0], depth)
+	case NodeNot:
+		if x.Children[0].isLeaf() {
+			r.leaf(x, x.Children[0], depth)
+			return
+		}
+		r.line(depth, r.code(x.Text)+" was "+r.result(x.Result))
+		r.render(x.Children[0], depth+1)
+	case NodeQuantifier:
+		text := r.code(x.Text) + " was " + r.result(x.Result)
+		var evalErr *EvalError
+		switch {
+		case errors.As(x.Err, &evalErr):
+			text += " because " + r.code(evalErr.Text) + " " + r.color(colorRed, "failed") + ": " + evalErr.Err.Error()
+		case isWildcard(x.Key):
+		case !x.Found:
+			text += " because " + r.code(x.Key) + " is missing"
+		case x.Value.Kind() != KindList:
+			text += " because " + r.code(x.Key) + " is no list"
+		}
+		r.line(depth, text)
+		for _, item := range x.Children {
+			r.line(depth+1, r.code(item.Key)+" was "+r.result(item.Result))
+			r.render(item.Children[0], depth+2)
+		}
+	case NodeMacro:
+		if x.isLeaf() {
+			r.leaf(x, x, depth)
+			return
+		}
+		r.line(depth, r.code(x.Text)+" was "+r.result(x.Result))
+		r.render(x.Children[0], depth+1)
+	case NodeWildcard:
+		r.line(depth, r.code(x.Text)+" was "+r.result(x.Result))
+		for _, child := range x.Children {
+			r.render(child, depth+1)
+		}
+	default:
+		r.leaf(x, x, depth)
+	}
+}
+
+// chain returns the conditions of a chain of the same logical operator.
+func (r *renderer) chain(x *Explanation, conditions []*Explanation) []*Explanation {
+	for _, child := range x.Children {
+		if child.Type == x.Type {
+			conditions = r.chain(child, conditions)
+		} else {
+			conditions = append(conditions, child)
+		}
+	}
+	return conditions
+}
+
+// leaf renders a single condition with the values of its keys and the error.
+func (r *renderer) leaf(x *Explanation, condition *Explanation, depth int) {
+	reasons := []string{}
+	var evalErr *EvalError
+	if err := firstError(condition); errors.As(err, &evalErr) {
+		reasons = append(reasons, r.code(evalErr.Text)+" "+r.color(colorRed, "failed")+": "+evalErr.Err.Error())
+	}
+
+	seen := map[string]bool{}
+	condition.walk(func(n *Explanation) {
+		if (n.Type != NodeKey && n.Type != NodePredicate) || n.Key == "" || seen[n.Key] {
+			return
+		}
+		seen[n.Key] = true
+
+		switch {
+		case !n.Found:
+			reasons = append(reasons, r.code(n.Key)+" is missing")
+		case n.Value.Kind() == KindNull:
+			reasons = append(reasons, r.code(n.Key)+" is null")
+		default:
+			reasons = append(reasons, r.code(n.Key)+"="+r.code(n.Value.String()))
+		}
+	})
+
+	text := r.code(x.Text) + " was " + r.result(x.Result)
+	if len(reasons) > 0 {
+		text += " because " + strings.Join(reasons, ", ")
+	}
+	r.line(depth, text)
+}
+
+func (r *renderer) line(depth int, text string) {
+	r.WriteString(strings.Repeat("  ", depth))
+	if r.style == RenderMarkdown {
+		r.WriteString("- ")
+	}
+	r.WriteString(text)
+	r.WriteString("\n")
+}
+
+func (r *renderer) result(t Truth) string {
+	text := strings.ToLower(t.String())
+	if r.style == RenderMarkdown {
+		return "**" + text + "**"
+	}
+	return r.color(truthColors[t], text)
+}
+
+func (r *renderer) color(color string, text string) string {
+	if r.style != RenderTerminal {
+		return text
+	}
+	return color + text + colorReset
+}
+
+func (r *renderer) code(text string) string {
+	if r.style != RenderMarkdown {
+		return text
+	}
+	return "`" + text + "`"
+}
+
+// walk calls fn for the node and its descendants in the order of the query.
+func (x *Explanation) walk(fn func(*Explanation)) {
+	fn(x)
+	for _, child := range x.Children {
+		child.walk(fn)
+	}
+}
+
+// firstError returns the first error of the node or its descendants.
+func firstError(x *Explanation) error {
+	var err error
+	x.walk(func(n *Explanation) {
+		if err == nil && n.Err != nil {
+			err = n.Err
+		}
+	})
+	return err
+}
@@ -0,0 +1,103 @@
+package simplequery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]string{"amount": "750", "a": "1", "net": "5", "zero": "0", "prices.eu": "5", "prices.us": "2"}
+
+	testCases := []struct {
+		query string
+		text  string
+	}{
+		{
+			query: "amount>1000 OR region=north",
+			text: "amount>1000 OR region=north was false\n" +
+				"  amount>1000 was false because amount=750\n" +
+				"  region=north was false because region is missing\n",
+		},
+		{
+			query: "a AND b AND (c OR !amount>1)",
+			text: "a AND b AND (c OR !amount>1) was false\n" +
+				"  a was true because a=1\n" +
+				"  b was false because b is missing\n" +
+				"  (c OR !amount>1) was not evaluated\n",
+		},
+		{
+			query: "!(a AND nothing IS NULL)",
+			text: "!(a AND nothing IS NULL) was false\n" +
+				"  a AND nothing IS NULL was true\n" +
+				"    a was true because a=1\n" +
+				"    nothing IS NULL was true because nothing is missing\n",
+		},
+		{
+			query: "amount + net / zero > 1",
+			text:  "amount + net / zero > 1 was unknown because net / zero failed: division by zero, amount=750, net=5, zero=0\n",
+		},
+		{
+			query: "prices.* > 3",
+			text: "prices.* > 3 was true\n" +
+				"  prices.* > 3 was true because prices.eu=5\n" +
+				"  prices.* > 3 was false because prices.us=2\n",
+		},
+		{
+			query: "ANY items: x>1",
+			text:  "ANY items: x>1 was false because items is missing\n",
+		},
+		{
+			query: "ANY amount: x>1",
+			text:  "ANY amount: x>1 was false because amount is no list\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		_, explanation, _ := Match(testCase.query, data)
+		assert.Equal(t, testCase.text, explanation.Render(RenderPlain), testCase.query)
+		assert.Equal(t, testCase.text, explanation.String(), testCase.query)
+	}
+
+	var explanation *Explanation
+	assert.Equal(t, "", explanation.String())
+
+	// a failed lookup of the list is no missing key
+	_, explanation, err := MatchSource("ANY items: x > 1", AnyMap{"items": []any{1, 2, 3}}, WithLimits(Limits{MaxListLength: 2}))
+	assert.ErrorIs(t, err, ErrListTooLong)
+	assert.Equal(t, "ANY items: x > 1 was unknown because items failed: list is too long: 3 items, the limit is 2\n", explanation.String())
+}
+
+func TestRenderItems(t *testing.T) {
+	t.Parallel()
+
+	data := AnyMap{"items": []any{map[string]any{"x": 2}, map[string]any{"x": nil}}}
+	_, explanation, err := MatchSource("ALL items: x>1", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "ALL items: x>1 was false\n"+
+		"  items[0] was true\n"+
+		"    x>1 was true because x=2\n"+
+		"  items[1] was false\n"+
+		"    x>1 was false because x is null\n", explanation.String())
+}
+
+func TestRenderStyles(t *testing.T) {
+	t.Parallel()
+
+	_, explanation, err := Match("amount>1000 OR region=north", map[string]string{"amount": "750", "region": "north"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "- `amount>1000 OR region=north` was **true**\n"+
+		"  - `amount>1000` was **false** because `amount`=`750`\n"+
+		"  - `region=north` was **true** because `region`=`north`\n", explanation.Render(RenderMarkdown))
+
+	assert.Equal(t, "amount>1000 OR region=north was \x1b[32mtrue\x1b[0m\n"+
+		"  amount>1000 was \x1b[31mfalse\x1b[0m because amount=750\n"+
+		"  region=north was \x1b[32mtrue\x1b[0m because region=north\n", explanation.Render(RenderTerminal))
+
+	_, explanation, err = Match("a / 0 > 1", map[string]string{"a": "1"})
+	assert.Error(t, err)
+	assert.Equal(t, "a / 0 > 1 was \x1b[33munknown\x1b[0m because a / 0 \x1b[31mfailed\x1b[0m: division by zero, a=1\n", explanation.Render(RenderTerminal))
+}
@@ -0,0 +1,135 @@
+package simplequery
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structFields caches the keys of the fields per struct type.
+var structFields sync.Map // map[reflect.Type]map[string][]int
+
+// structSource looks up the fields of a struct.
+type structSource struct {
+	value  reflect.Value
+	fields map[string][]int
+}
+
+// Struct is a DataSource of the exported fields of a struct or a pointer to a
+// struct. The key of a field is the name in its sq tag, the name in its json
+// tag or the field name, a field with the tag "-" is skipped. Fields of
+// embedded structs are keys of the struct unless the struct has a field with
+// the same key. Nested structs are objects, so their fields can be used as
+// path like customer.address.country.
+func Struct(value any) DataSource {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return StringMap(nil)
+	}
+
+	return &structSource{value: rv, fields: fieldsOf(rv.Type())}
+}
+
+// Lookup returns the value of the field with the key. A field of a nil
+// embedded struct is missing.
+func (s *structSource) Lookup(key string) (Value, bool) {
+	index, ok := s.fields[key]
+	if !ok {
+		return Value{}, false
+	}
+
+	field, err := s.value.FieldByIndexErr(index)
+	if err != nil || !field.CanInterface() {
+		return Value{}, false
+	}
+	return ValueOf(field.Interface()), true
+}
+
+// ScanPrefix calls fn with the keys of the fields starting with the prefix.
+func (s *structSource) ScanPrefix(prefix string, fn func(key string, value Value) bool) {
+	for key := range s.fields {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if value, ok := s.Lookup(key); ok && !fn(key, value) {
+			return
+		}
+	}
+}
+
+// fieldsOf returns the index of each key of the struct type.
+func fieldsOf(t reflect.Type) map[string][]int {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.(map[string][]int)
+	}
+
+	fields := map[string][]int{}
+	collectFields(t, nil, fields)
+
+	actual, _ := structFields.LoadOrStore(t, fields)
+	return actual.(map[string][]int)
+}
+
+// collectFields adds the fields of the struct type before the fields of its embedded structs.
+func collectFields(t reflect.Type, index []int, fields map[string][]int) {
+	embedded := []reflect.StructField{}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = append(append([]int{}, index...), i)
+		}
+	}
+
+	for _, field := range embedded {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		collectFields(fieldType, append(append([]int{}, index...), field.Index...), fields)
+	}
+}
+
+// fieldName returns the name of the field in the sq or json tag and false if
+// the field is skipped.
+func fieldName(field reflect.StructField) (string, bool) {
+	for _, key := range []string{"sq", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return "", false
+		}
+		if name != "" {
+			return name, true
+		}
+	}
+
+	return "", true
+}
@@ -0,0 +1,137 @@
+package simplequery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testAddress struct {
+	Country string `json:"country"`
+}
+
+type testBase struct {
+	ID      int       `sq:"id"`
+	Created time.Time `json:"created,omitempty"`
+	Amount  float64
+}
+
+type testAudit struct {
+	Author string `json:"author"`
+}
+
+type testStatus int
+
+func (s testStatus) String() string {
+	return [...]string{"open", "done"}[s]
+}
+
+type testOrder struct {
+	testBase
+	*testAudit
+	Amount   float64 `sq:"amount" json:"total"`
+	Customer struct {
+		Name    string       `json:"name"`
+		Address *testAddress `json:"address"`
+	} `json:"customer"`
+	Items    []testItem `json:"items"`
+	Status   testStatus `json:"status"`
+	Currency string     `json:"-"`
+	Deleted  *time.Time `json:"deleted"`
+	Priority uint8      `json:"priority"`
+	note     string
+}
+
+type testItem struct {
+	SKU string `json:"sku"`
+	Qty int    `json:"qty"`
+}
+
+func TestStruct(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	order := testOrder{
+		testBase: testBase{ID: 7, Created: created, Amount: 1},
+		Amount:   120.5,
+		Items:    []testItem{{SKU: "X1", Qty: 3}},
+		Status:   1,
+		Currency: "EUR",
+		Priority: 2,
+		note:     "secret",
+	}
+	order.Customer.Name = "Jane"
+	order.Customer.Address = &testAddress{Country: "DE"}
+
+	data := Struct(&order)
+
+	testCases := []struct {
+		key   string
+		value Value
+		found bool
+	}{
+		{key: "amount", value: NumberValue(120.5), found: true},
+		{key: "id", value: NumberValue(7), found: true},
+		{key: "created", value: TimeValue(created), found: true},
+		{key: "status", value: StringValue("done"), found: true},
+		{key: "deleted", value: NullValue(), found: true},
+		{key: "priority", value: NumberValue(2), found: true},
+		{key: "total"},
+		{key: "Amount", value: NumberValue(1), found: true},
+		{key: "Currency"},
+		{key: "note"},
+		{key: "author"},
+	}
+
+	for _, testCase := range testCases {
+		value, found := data.Lookup(testCase.key)
+		assert.Equal(t, testCase.found, found, testCase.key)
+		if testCase.found {
+			assert.Equal(t, testCase.value, value, testCase.key)
+		}
+	}
+
+	value, found := lookup(data, "customer.address.country")
+	assert.True(t, found)
+	assert.Equal(t, StringValue("DE"), value)
+
+	value, found = lookup(data, "items[0].qty")
+	assert.True(t, found)
+	assert.Equal(t, NumberValue(3), value)
+
+	order.testAudit = &testAudit{Author: "joe"}
+	value, found = Struct(order).Lookup("author")
+	assert.True(t, found)
+	assert.Equal(t, StringValue("joe"), value)
+
+	_, found = Struct("no struct").Lookup("amount")
+	assert.False(t, found)
+	_, found = Struct((*testOrder)(nil)).Lookup("amount")
+	assert.False(t, found)
+}
+
+func TestMatchStruct(t *testing.T) {
+	t.Parallel()
+
+	order := testOrder{Amount: 120.5, Items: []testItem{{SKU: "X1", Qty: 3}, {SKU: "X2", Qty: 1}}}
+	order.Customer.Address = &testAddress{Country: "DE"}
+
+	testCases := []struct {
+		query string
+		ok    bool
+	}{
+		{query: "amount > 100 AND customer.address.country = DE", ok: true},
+		{query: `ANY items: (sku = "X2" AND qty < 2)`, ok: true},
+		{query: "status = open", ok: true},
+		{query: "deleted IS NULL AND deleted IS NOT MISSING", ok: true},
+		{query: "customer.name IS EMPTY", ok: true},
+		{query: "author", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		ok, _, err := MatchSource(testCase.query, Struct(order))
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+	}
+}
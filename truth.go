@@ -0,0 +1,62 @@
+package simplequery
+
+// Truth is the result of a query in three-valued logic.
+type Truth int
+
+const (
+	False Truth = iota
+	True
+	Unknown
+)
+
+var truths = []string{
+	False:   "FALSE",
+	True:    "TRUE",
+	Unknown: "UNKNOWN",
+}
+
+// String name of a truth value
+func (t Truth) String() string {
+	return truths[t]
+}
+
+func truthOf(b bool) Truth {
+	if b {
+		return True
+	}
+	return False
+}
+
+// and combines two values with Kleene logic, FALSE wins over UNKNOWN.
+func (t Truth) and(o Truth) Truth {
+	if t == False || o == False {
+		return False
+	}
+	if t == Unknown || o == Unknown {
+		return Unknown
+	}
+	return True
+}
+
+// or combines two values with Kleene logic, TRUE wins over UNKNOWN.
+func (t Truth) or(o Truth) Truth {
+	if t == True || o == True {
+		return True
+	}
+	if t == Unknown || o == Unknown {
+		return Unknown
+	}
+	return False
+}
+
+// not negates the value, UNKNOWN stays UNKNOWN.
+func (t Truth) not() Truth {
+	switch t {
+	case True:
+		return False
+	case False:
+		return True
+	default:
+		return Unknown
+	}
+}
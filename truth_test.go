@@ -0,0 +1,45 @@
+package simplequery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruthToString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "FALSE", False.String())
+	assert.Equal(t, "TRUE", True.String())
+	assert.Equal(t, "UNKNOWN", Unknown.String())
+}
+
+func TestTruthLogic(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a   Truth
+		b   Truth
+		and Truth
+		or  Truth
+	}{
+		{a: True, b: True, and: True, or: True},
+		{a: True, b: False, and: False, or: True},
+		{a: True, b: Unknown, and: Unknown, or: True},
+		{a: False, b: False, and: False, or: False},
+		{a: False, b: Unknown, and: False, or: Unknown},
+		{a: Unknown, b: Unknown, and: Unknown, or: Unknown},
+	}
+
+	for _, testCase := range testCases {
+		desc := testCase.a.String() + " " + testCase.b.String()
+		assert.Equal(t, testCase.and, testCase.a.and(testCase.b), desc)
+		assert.Equal(t, testCase.and, testCase.b.and(testCase.a), desc)
+		assert.Equal(t, testCase.or, testCase.a.or(testCase.b), desc)
+		assert.Equal(t, testCase.or, testCase.b.or(testCase.a), desc)
+	}
+
+	assert.Equal(t, False, True.not())
+	assert.Equal(t, True, False.not())
+	assert.Equal(t, Unknown, Unknown.not())
+}
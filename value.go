@@ -0,0 +1,348 @@
+package simplequery
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind of a value
+type Kind int
+
+const (
+	KindNull Kind = iota
+	KindString
+	KindNumber
+	KindBool
+	KindTime
+	KindList
+	KindObject
+)
+
+var kinds = []string{
+	KindNull:   "null",
+	KindString: "string",
+	KindNumber: "number",
+	KindBool:   "bool",
+	KindTime:   "time",
+	KindList:   "list",
+	KindObject: "object",
+}
+
+// timeLayouts are tried in order to parse a text as time.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// String name of a kind
+func (k Kind) String() string {
+	if k == KindAny {
+		return "any"
+	}
+	if k < 0 || int(k) >= len(kinds) {
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+	return kinds[k]
+}
+
+// ErrDivisionByZero is returned by / and % with a zero divisor.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// Value is a typed value of a literal, a key or a computed expression.
+//
+// Values of different kinds are converted when they are compared:
+//
+//	null    is only equal to null and can not be ordered.
+//	bool    is compared with the truthiness of the other value and can not be ordered.
+//	number  is compared with a text as number, if the text is a number, and as text otherwise.
+//	time    is compared with a text as time, if the text is a time, and with a number as Unix seconds.
+//	string  is ordered with another text as number or, if both are no numbers, as time.
+//	list    is equal to a list with equal items and can not be ordered.
+//	object  is never equal to another value and can not be ordered.
+//
+// Calculations need numbers, texts are parsed as numbers.
+type Value struct {
+	kind Kind
+	str  string
+	num  float64
+	b    bool
+	t    time.Time
+	list []Value
+	obj  DataSource
+}
+
+// NullValue returns the null value.
+func NullValue() Value {
+	return Value{kind: KindNull}
+}
+
+// StringValue wraps a string.
+func StringValue(s string) Value {
+	return Value{kind: KindString, str: s}
+}
+
+// NumberValue wraps a number.
+func NumberValue(f float64) Value {
+	return Value{kind: KindNumber, num: f, str: strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+// BoolValue wraps a bool.
+func BoolValue(b bool) Value {
+	return Value{kind: KindBool, b: b}
+}
+
+// TimeValue wraps a time.
+func TimeValue(t time.Time) Value {
+	return Value{kind: KindTime, t: t}
+}
+
+// ListValue wraps a list of values.
+func ListValue(items []Value) Value {
+	return Value{kind: KindList, list: items}
+}
+
+// ObjectValue wraps a nested document. Its fields are looked up in the DataSource.
+func ObjectValue(fields DataSource) Value {
+	return Value{kind: KindObject, obj: fields}
+}
+
+// numberLiteral keeps the source text of a number. Text which is no valid number,
+// e.g. 12,34, stays a string and fails on numeric operators.
+func numberLiteral(text string) Value {
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return StringValue(text)
+	}
+
+	return Value{kind: KindNumber, num: f, str: text}
+}
+
+// Kind of the value
+func (v Value) Kind() Kind {
+	return v.kind
+}
+
+// String returns the value as text.
+func (v Value) String() string {
+	switch v.kind {
+	case KindNull:
+		return "null"
+	case KindBool:
+		return strconv.FormatBool(v.b)
+	case KindTime:
+		return v.t.Format(time.RFC3339Nano)
+	case KindList:
+		items := make([]string, len(v.list))
+		for i, item := range v.list {
+			items[i] = item.String()
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case KindObject:
+		return "object"
+	default:
+		return v.str
+	}
+}
+
+// List returns the items of a list, other kinds have no items.
+func (v Value) List() []Value {
+	return v.list
+}
+
+// Object returns the fields of a nested document and whether the value is an object.
+func (v Value) Object() (DataSource, bool) {
+	return v.obj, v.kind == KindObject
+}
+
+// Number returns the value as number. Strings are parsed, other kinds fail.
+func (v Value) Number() (float64, error) {
+	switch v.kind {
+	case KindNumber:
+		return v.num, nil
+	case KindString:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.str), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", v.str)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%s is not a number", v.String())
+	}
+}
+
+// Time returns the value as time. Texts in RFC 3339 format or as date are
+// parsed, numbers are Unix seconds, other kinds fail.
+func (v Value) Time() (time.Time, error) {
+	switch v.kind {
+	case KindTime:
+		return v.t, nil
+	case KindNumber:
+		sec, frac := math.Modf(v.num)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case KindString:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v.str)); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%q is not a time", v.str)
+	default:
+		return time.Time{}, fmt.Errorf("%s is not a time", v.String())
+	}
+}
+
+// Truthy reports whether the value counts as true.
+func (v Value) Truthy() bool {
+	switch v.kind {
+	case KindBool:
+		return v.b
+	case KindNumber:
+		return v.num != 0
+	case KindString:
+		return isTruthy(v.str)
+	case KindTime:
+		return !v.t.IsZero()
+	case KindList:
+		return len(v.list) > 0
+	case KindObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// compare two values with a comparison operator, see Value for the conversions.
+func compare(operator Token, left Value, right Value) (bool, error) {
+	switch operator {
+	case EQ:
+		return equal(left, right), nil
+	case NE:
+		return !equal(left, right), nil
+	}
+
+	c, err := order(left, right)
+	if err != nil {
+		return false, err
+	}
+
+	switch operator {
+	case GT:
+		return c > 0, nil
+	case GTE:
+		return c >= 0, nil
+	case LT:
+		return c < 0, nil
+	case LTE:
+		return c <= 0, nil
+	}
+
+	return false, fmt.Errorf("unknown operator %s", operator.String())
+}
+
+// order returns -1, 0 or 1 if the left value is less, equal or greater than the right value.
+func order(left Value, right Value) (int, error) {
+	if left.kind == KindTime || right.kind == KindTime || (left.kind == KindString && right.kind == KindString) {
+		l, lErr := left.Time()
+		r, rErr := right.Time()
+		if lErr == nil && rErr == nil {
+			return l.Compare(r), nil
+		}
+		if left.kind == KindTime || right.kind == KindTime {
+			return 0, errors.Join(lErr, rErr)
+		}
+	}
+
+	l, err := left.Number()
+	if err != nil {
+		return 0, err
+	}
+	r, err := right.Number()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case l < r:
+		return -1, nil
+	case l > r:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func equal(left Value, right Value) bool {
+	switch {
+	case left.kind == KindNull || right.kind == KindNull:
+		return left.kind == right.kind
+	case left.kind == KindObject || right.kind == KindObject:
+		return false
+	case left.kind == KindList || right.kind == KindList:
+		if left.kind != right.kind || len(left.list) != len(right.list) {
+			return false
+		}
+		for i := range left.list {
+			if !equal(left.list[i], right.list[i]) {
+				return false
+			}
+		}
+		return true
+	case left.kind == KindBool:
+		return left.b == right.Truthy()
+	case right.kind == KindBool:
+		return right.b == left.Truthy()
+	case left.kind == KindTime || right.kind == KindTime:
+		l, lErr := left.Time()
+		r, rErr := right.Time()
+		if lErr == nil && rErr == nil {
+			return l.Equal(r)
+		}
+		return left.String() == right.String()
+	case left.kind == KindNumber || right.kind == KindNumber:
+		l, lErr := left.Number()
+		r, rErr := right.Number()
+		if lErr == nil && rErr == nil {
+			return l == r
+		}
+	}
+
+	return left.str == right.str
+}
+
+// calculate applies an arithmetic operator to two numbers.
+func calculate(operator Token, left Value, right Value) (Value, error) {
+	l, err := left.Number()
+	if err != nil {
+		return Value{}, err
+	}
+	r, err := right.Number()
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch operator {
+	case PLUS:
+		return NumberValue(l + r), nil
+	case MINUS:
+		return NumberValue(l - r), nil
+	case MUL:
+		return NumberValue(l * r), nil
+	case DIV:
+		if r == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		return NumberValue(l / r), nil
+	case MOD:
+		if r == 0 {
+			return Value{}, ErrDivisionByZero
+		}
+		return NumberValue(math.Mod(l, r)), nil
+	}
+
+	return Value{}, fmt.Errorf("unknown operator %s", operator.String())
+}
@@ -0,0 +1,197 @@
+package simplequery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindToString(t *testing.T) {
+	t.Parallel()
+
+	for _, kind := range []Kind{KindNull, KindString, KindNumber, KindBool, KindTime, KindList, KindObject} {
+		assert.Greater(t, len(kind.String()), 0)
+	}
+
+	assert.Equal(t, "any", KindAny.String())
+	assert.Equal(t, "kind(42)", Kind(42).String())
+	assert.Equal(t, "kind(-2)", Kind(-2).String())
+}
+
+func TestValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, KindNull, NullValue().Kind())
+	assert.Equal(t, "null", NullValue().String())
+	assert.Equal(t, "abc", StringValue("abc").String())
+	assert.Equal(t, "12.5", NumberValue(12.5).String())
+	assert.Equal(t, "true", BoolValue(true).String())
+	assert.Equal(t, "12.50", numberLiteral("12.50").String())
+	assert.Equal(t, KindString, numberLiteral("12,50").Kind())
+
+	number, err := StringValue(" 12.5").Number()
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, number)
+
+	_, err = StringValue("abc").Number()
+	assert.Error(t, err)
+	_, err = BoolValue(true).Number()
+	assert.Error(t, err)
+
+	assert.True(t, BoolValue(true).Truthy())
+	assert.True(t, NumberValue(-1).Truthy())
+	assert.True(t, StringValue("yes").Truthy())
+	assert.False(t, NumberValue(0).Truthy())
+	assert.False(t, NullValue().Truthy())
+	assert.True(t, TimeValue(time.Unix(0, 0)).Truthy())
+	assert.False(t, TimeValue(time.Time{}).Truthy())
+
+	list := ListValue([]Value{StringValue("a"), NumberValue(1)})
+	assert.Equal(t, "[a, 1]", list.String())
+	assert.Len(t, list.List(), 2)
+	assert.True(t, list.Truthy())
+	assert.False(t, ListValue(nil).Truthy())
+
+	object := ObjectValue(StringMap{"a": "b"})
+	fields, ok := object.Object()
+	assert.True(t, ok)
+	value, _ := fields.Lookup("a")
+	assert.Equal(t, StringValue("b"), value)
+	_, ok = list.Object()
+	assert.False(t, ok)
+}
+
+func TestValueTime(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-03-01T00:00:00Z", TimeValue(date).String())
+
+	testCases := []struct {
+		value   Value
+		time    time.Time
+		isError bool
+	}{
+		{value: TimeValue(date), time: date},
+		{value: StringValue("2024-03-01"), time: date},
+		{value: StringValue("2024-03-01T00:00:00Z"), time: date},
+		{value: StringValue("2024-03-01 12:30:00"), time: date.Add(12*time.Hour + 30*time.Minute)},
+		{value: NumberValue(float64(date.Unix())), time: date},
+		{value: StringValue("tomorrow"), isError: true},
+		{value: BoolValue(true), isError: true},
+		{value: NullValue(), isError: true},
+	}
+
+	for _, testCase := range testCases {
+		result, err := testCase.value.Time()
+		if testCase.isError {
+			assert.Error(t, err, testCase.value.String())
+			continue
+		}
+		assert.NoError(t, err, testCase.value.String())
+		assert.True(t, testCase.time.Equal(result), testCase.value.String())
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		operator Token
+		left     Value
+		right    Value
+		ok       bool
+		isError  bool
+	}{
+		{operator: EQ, left: StringValue("abc"), right: StringValue("abc"), ok: true},
+		{operator: EQ, left: StringValue("1234"), right: numberLiteral("1234"), ok: true},
+		{operator: EQ, left: StringValue("1234.0"), right: numberLiteral("1234"), ok: true},
+		{operator: EQ, left: StringValue("abc"), right: numberLiteral("1234"), ok: false},
+		{operator: EQ, left: StringValue("12,34"), right: numberLiteral("12,34"), ok: true},
+		{operator: EQ, left: StringValue("yes"), right: BoolValue(true), ok: true},
+		{operator: EQ, left: BoolValue(false), right: StringValue("0"), ok: true},
+		{operator: EQ, left: NullValue(), right: NullValue(), ok: true},
+		{operator: EQ, left: StringValue(""), right: NullValue(), ok: false},
+		{operator: NE, left: StringValue("abc"), right: StringValue("abc"), ok: false},
+		{operator: NE, left: StringValue(""), right: NullValue(), ok: true},
+		{operator: GT, left: StringValue("12"), right: numberLiteral("11.5"), ok: true},
+		{operator: GTE, left: NumberValue(12), right: NumberValue(12), ok: true},
+		{operator: LT, left: NumberValue(-1), right: NumberValue(0), ok: true},
+		{operator: LTE, left: NumberValue(1), right: NumberValue(0), ok: false},
+		{operator: GT, left: StringValue("abc"), right: NumberValue(1), isError: true},
+		{operator: LT, left: NumberValue(1), right: numberLiteral("12,34"), isError: true},
+		{operator: LTE, left: NumberValue(1), right: BoolValue(true), isError: true},
+		{operator: PLUS, left: NumberValue(1), right: NumberValue(1), isError: true},
+		{operator: EQ, left: TimeValue(date), right: StringValue("2024-03-01"), ok: true},
+		{operator: EQ, left: TimeValue(date), right: StringValue("abc"), ok: false},
+		{operator: EQ, left: TimeValue(date), right: NumberValue(float64(date.Unix())), ok: true},
+		{operator: EQ, left: TimeValue(date), right: BoolValue(true), ok: true},
+		{operator: EQ, left: TimeValue(date), right: NullValue(), ok: false},
+		{operator: GT, left: TimeValue(date), right: StringValue("2024-02-29"), ok: true},
+		{operator: LT, left: StringValue("2024-03-02"), right: TimeValue(date), ok: false},
+		{operator: GTE, left: StringValue("2024-03-01"), right: StringValue("2024-02-01"), ok: true},
+		{operator: LT, left: StringValue("9"), right: StringValue("10"), ok: true},
+		{operator: GT, left: TimeValue(date), right: StringValue("abc"), isError: true},
+		{operator: GT, left: TimeValue(date), right: BoolValue(true), isError: true},
+		{operator: EQ, left: ListValue([]Value{NumberValue(1)}), right: ListValue([]Value{StringValue("1")}), ok: true},
+		{operator: EQ, left: ListValue([]Value{NumberValue(1)}), right: ListValue(nil), ok: false},
+		{operator: EQ, left: ListValue(nil), right: StringValue("[]"), ok: false},
+		{operator: EQ, left: ObjectValue(StringMap{}), right: ObjectValue(StringMap{}), ok: false},
+		{operator: GT, left: ListValue(nil), right: NumberValue(1), isError: true},
+	}
+
+	for _, testCase := range testCases {
+		desc := testCase.left.String() + testCase.operator.String() + testCase.right.String()
+
+		ok, err := compare(testCase.operator, testCase.left, testCase.right)
+		assert.Equal(t, testCase.ok, ok, desc)
+		if testCase.isError {
+			assert.Error(t, err, desc)
+		} else {
+			assert.NoError(t, err, desc)
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		operator Token
+		left     Value
+		right    Value
+		result   float64
+		err      error
+	}{
+		{operator: PLUS, left: NumberValue(1.5), right: StringValue("2"), result: 3.5},
+		{operator: MINUS, left: NumberValue(1), right: NumberValue(3), result: -2},
+		{operator: MUL, left: StringValue("4"), right: StringValue("2.5"), result: 10},
+		{operator: DIV, left: NumberValue(9), right: NumberValue(2), result: 4.5},
+		{operator: MOD, left: NumberValue(9), right: NumberValue(4), result: 1},
+		{operator: DIV, left: NumberValue(9), right: NumberValue(0), err: ErrDivisionByZero},
+		{operator: MOD, left: NumberValue(9), right: StringValue("0"), err: ErrDivisionByZero},
+	}
+
+	for _, testCase := range testCases {
+		desc := testCase.left.String() + testCase.operator.String() + testCase.right.String()
+
+		result, err := calculate(testCase.operator, testCase.left, testCase.right)
+		if testCase.err != nil {
+			assert.ErrorIs(t, err, testCase.err, desc)
+			continue
+		}
+
+		assert.NoError(t, err, desc)
+		number, err := result.Number()
+		assert.NoError(t, err, desc)
+		assert.Equal(t, testCase.result, number, desc)
+	}
+
+	_, err := calculate(PLUS, StringValue("abc"), NumberValue(1))
+	assert.Error(t, err)
+	_, err = calculate(EQ, NumberValue(1), NumberValue(1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package simplequery
+
+import (
+	"sort"
+	"strings"
+)
+
+// match is a key which matches a wildcard pattern.
+type match struct {
+	key   string
+	value Value
+}
+
+func isWildcard(key string) bool {
+	return strings.HasSuffix(key, ".*")
+}
+
+// scan returns the keys matching a wildcard pattern like approval.*, sorted by
+// key. The wildcard matches one segment of the flat keys of the data, e.g.
+// approval.alice, and the fields of the object approval.
+func scan(data DataSource, pattern string) []match {
+	prefix := strings.TrimSuffix(pattern, "*")
+	matches := []match{}
+	seen := map[string]bool{}
+
+	add := func(key string, value Value) bool {
+		if rest := key[len(prefix):]; rest != "" && !isPath(rest) && !seen[key] {
+			seen[key] = true
+			matches = append(matches, match{key: key, value: value})
+		}
+		return true
+	}
+
+	if scanner, ok := data.(KeyScanner); ok {
+		scanner.ScanPrefix(prefix, add)
+	}
+
+	if parent, ok := lookup(data, strings.TrimSuffix(prefix, ".")); ok {
+		if fields, ok := parent.Object(); ok {
+			if scanner, ok := fields.(KeyScanner); ok {
+				scanner.ScanPrefix("", func(key string, value Value) bool {
+					return add(prefix+key, value)
+				})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i].key < matches[j].key })
+	return matches
+}
+
+// wildcardScope binds a wildcard pattern to the value of a matching key.
+type wildcardScope struct {
+	DataSource
+	pattern string
+	value   Value
+	found   bool
+}
+
+func (s wildcardScope) Lookup(key string) (Value, bool) {
+	if key == s.pattern {
+		return s.value, s.found
+	}
+	return s.DataSource.Lookup(key)
+}
+
+// wildcardKeys returns the keys with a wildcard of a condition.
+func wildcardKeys(n node) []*keyNode {
+	switch n := n.(type) {
+	case *keyNode:
+		if isWildcard(n.name) {
+			return []*keyNode{n}
+		}
+	case *isNode:
+		return wildcardKeys(n.key)
+	case *binaryNode:
+		if n.op != AND && n.op != OR {
+			return append(wildcardKeys(n.left), wildcardKeys(n.right)...)
+		}
+	case *negNode:
+		return wildcardKeys(n.x)
+	case *groupNode:
+		return wildcardKeys(n.x)
+	case *callNode:
+		keys := []*keyNode{}
+		for _, arg := range n.args {
+			keys = append(keys, wildcardKeys(arg)...)
+		}
+		return keys
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package simplequery
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+
+	keys := func(matches []match) []string {
+		result := []string{}
+		for _, m := range matches {
+			result = append(result, m.key)
+		}
+		return result
+	}
+
+	flat := StringMap{"approval.bob": "approved", "approval.alice": "rejected", "approval.alice.at": "monday", "approvals": "2"}
+	assert.Equal(t, []string{"approval.alice", "approval.bob"}, keys(scan(flat, "approval.*")))
+
+	nested := AnyMap{
+		"approval":     map[string]any{"carol": "approved", "dave": "approved"},
+		"approval.bob": "approved",
+		"items":        []any{map[string]any{"sku": "X1", "qty": 1}},
+		"typed":        map[string]int{"a": 1},
+	}
+	assert.Equal(t, []string{"approval.bob", "approval.carol", "approval.dave"}, keys(scan(nested, "approval.*")))
+	assert.Equal(t, []string{"items[0].qty", "items[0].sku"}, keys(scan(nested, "items[0].*")))
+	assert.Equal(t, []string{"typed.a"}, keys(scan(nested, "typed.*")))
+	assert.Empty(t, scan(nested, "unknownKey.*"))
+
+	namespaces := Namespaces{"instance": flat, "env": DataSourceFunc(func(string) (Value, bool) { return Value{}, false })}
+	assert.Equal(t, []string{"instance:approval.alice", "instance:approval.bob"}, keys(scan(namespaces, "instance:approval.*")))
+	assert.Empty(t, scan(namespaces, "env:approval.*"))
+
+	assert.Equal(t, []string{"approval.alice"}, keys(scan(URLValues(url.Values{"approval.alice": {"x"}, "other": {"y"}}), "approval.*")))
+	assert.Equal(t, []string{"X-Approval-Alice"}, keys(scan(HTTPHeader(http.Header{"X-Approval-Alice": {"x"}}), "x-approval-*")))
+
+	type approvals struct {
+		Alice string `json:"alice"`
+		Bob   string `json:"bob"`
+	}
+	assert.Equal(t, []string{"approval.alice", "approval.bob"}, keys(scan(AnyMap{"approval": approvals{}}, "approval.*")))
+}
+
+func TestMatchWildcards(t *testing.T) {
+	t.Parallel()
+
+	data := StringMap{"approval.alice": "approved", "approval.bob": "rejected", "review.carol": "ok", "amount": "10"}
+
+	testCases := []struct {
+		query   string
+		ok      bool
+		details []bool
+	}{
+		{query: "approval.*=approved", ok: true, details: []bool{true}},
+		{query: "approval.*=pending", ok: false, details: []bool{false}},
+		{query: "!approval.*=pending", ok: true, details: []bool{true}},
+		{query: "ALL approval.*=approved", ok: false, details: []bool{true, false, false}},
+		{query: "ANY approval.* = rejected", ok: true, details: []bool{false, true, true}},
+		{query: "NONE approval.* = pending", ok: true, details: []bool{false, false, true}},
+		{query: "ALL review.*=ok AND amount > 5", ok: true, details: []bool{true, true, true}},
+		{query: "ALL unknownKey.*=ok", ok: true, details: []bool{true}},
+		{query: "EXISTS approval.*", ok: true, details: []bool{true}},
+		{query: "EXISTS unknownKey.*", ok: false, details: []bool{false}},
+		{query: "NOT EXISTS unknownKey.*", ok: true, details: []bool{true}},
+		{query: "EXISTS amount AND !EXISTS unknownKey", ok: true, details: []bool{true, true}},
+		{query: "approval.*", ok: true, details: []bool{true}},
+		{query: "unknownKey.*=x", ok: false, details: []bool{false}},
+		{query: "len(approval.*) = 8", ok: true, details: []bool{true}},
+		{query: "approval.* IS EMPTY", ok: false, details: []bool{false}},
+	}
+
+	for _, testCase := range testCases {
+		ok, explanation, err := MatchSource(testCase.query, data)
+		assert.NoError(t, err, testCase.query)
+		assert.Equal(t, testCase.ok, ok, testCase.query)
+		assert.Equal(t, testCase.details, explanation.Details(), testCase.query)
+	}
+
+	result, _, err := EvaluateSource("unknownKey.*=x", data, WithUnknown())
+	assert.NoError(t, err)
+	assert.Equal(t, Unknown, result)
+
+	for _, query := range []string{"ALL amount=1", "ALL approval.* = review.* AND len(approval.*) = len(review.*)", "len(approval.*) = len(review.*)", "EXISTS (a)", "EXISTS 1"} {
+		_, err := Compile(query)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestMatchWildcardSources(t *testing.T) {
+	t.Parallel()
+
+	instance := AnyMap{
+		"approval": map[string]any{"alice": map[string]any{"state": "approved"}, "bob": map[string]any{"state": "approved"}},
+	}
+
+	ok, explanation, err := MatchSource("ALL approval.*: state = approved", instance)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []bool{true, true, true}, explanation.Details())
+
+	ok, _, err = MatchSource("ALL approval.* = x", Namespaces{"instance": instance}, WithNamespaces("instance"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, _, err = MatchSource("EXISTS instance:approval.*", Namespaces{"instance": instance}, WithNamespaces("", "instance"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = MatchJSON("approval.*=approved AND ALL vote.*=yes", []byte(`{"approval.alice": "approved", "vote": {"a": "yes"}, "vote.b": "yes", "other": 1}`))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}